@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/data-ru/gufu"
+)
+
+// gufu descriptografar [opções] entrada [saída]
+// Descriptografa os corpos de uma captura HAR ou um único corpo (requisição ou resposta) da API do aplicativo.
+func executarDescriptografar(args []string) error {
+	flags := flag.NewFlagSet("descriptografar", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Uso: gufu descriptografar [opções] entrada [saída]\n\nDescriptografa os corpos das requisições e respostas do aplicativo em um arquivo HAR, mantendo o resto da captura.\nSe a entrada não for um HAR, ela é tratada como um único corpo ({\"requestParams\": ...} ou uma resposta).\nUse - para ler da entrada padrão. Sem saída, o resultado é escrito na saída padrão.\n\nOpções:")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	entrada, err := lerEntradaCaptura(flags)
+	if err != nil {
+		return err
+	}
+
+	descriptografado, resumo, err := gufu.DescriptografarArquivoHAR(entrada)
+	if err == nil {
+		fmt.Fprintf(os.Stderr, "%d requisições e %d respostas descriptografadas\n", resumo.Requisicoes, resumo.Respostas)
+		return escreverSaidaCaptura(flags, descriptografado)
+	}
+	if !errors.Is(err, gufu.ErrHARInvalido) {
+		return err
+	}
+
+	texto, err := gufu.DescriptografarRequisicao(string(entrada))
+	if err != nil {
+		if texto, err = gufu.Descriptografar(string(bytes.TrimSpace(entrada))); err != nil {
+			return fmt.Errorf("a entrada não é um HAR nem um envelope do aplicativo: %w", err)
+		}
+	}
+	var formatado bytes.Buffer
+	if json.Indent(&formatado, []byte(texto), "", "  ") != nil {
+		return escreverSaidaCaptura(flags, []byte(texto))
+	}
+	return escreverSaidaCaptura(flags, formatado.Bytes())
+}
+
+// gufu criptografar [opções] entrada [saída]
+// Faz o inverso de gufu descriptografar.
+func executarCriptografar(args []string) error {
+	flags := flag.NewFlagSet("criptografar", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Uso: gufu criptografar [opções] entrada [saída]\n\nCriptografa de novo os corpos descriptografados por gufu descriptografar ou gufu proxy em um arquivo HAR.\nSe a entrada não for um HAR, ela é tratada como um único JSON, criptografado como uma requisição (ou uma resposta, com -resposta).\nUse - para ler da entrada padrão. Sem saída, o resultado é escrito na saída padrão.\n\nOpções:")
+		flags.PrintDefaults()
+	}
+	resposta := flags.Bool("resposta", false, "criptografa um JSON avulso como uma resposta da API em vez de uma requisição")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	entrada, err := lerEntradaCaptura(flags)
+	if err != nil {
+		return err
+	}
+
+	criptografado, resumo, err := gufu.CriptografarArquivoHAR(entrada)
+	if err == nil {
+		fmt.Fprintf(os.Stderr, "%d requisições e %d respostas criptografadas\n", resumo.Requisicoes, resumo.Respostas)
+		return escreverSaidaCaptura(flags, criptografado)
+	}
+	if !errors.Is(err, gufu.ErrHARInvalido) {
+		return err
+	}
+
+	var compacto bytes.Buffer
+	if err := json.Compact(&compacto, entrada); err != nil {
+		return fmt.Errorf("a entrada não é um HAR nem um JSON: %w", err)
+	}
+	criptografar := gufu.Criptografar
+	if *resposta {
+		criptografar = gufu.CriptografarResposta
+	}
+	texto, err := criptografar(compacto.String())
+	if err != nil {
+		return err
+	}
+	return escreverSaidaCaptura(flags, []byte(texto))
+}
+
+func lerEntradaCaptura(flags *flag.FlagSet) ([]byte, error) {
+	if flags.NArg() < 1 || flags.NArg() > 2 {
+		flags.Usage()
+		return nil, errors.New("informe o arquivo de entrada")
+	}
+	if flags.Arg(0) == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(flags.Arg(0))
+}
+
+// Escreve o resultado no arquivo de saída ou na saída padrão
+func escreverSaidaCaptura(flags *flag.FlagSet, resultado []byte) error {
+	if !bytes.HasSuffix(resultado, []byte("\n")) {
+		resultado = append(resultado, '\n')
+	}
+	if flags.NArg() == 2 && flags.Arg(1) != "-" {
+		return os.WriteFile(flags.Arg(1), resultado, 0o644)
+	}
+	_, err := os.Stdout.Write(resultado)
+	return err
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/data-ru/gufu"
+)
+
+// gufu inspecionar [opções] entrada
+// Identifica o formato de um corpo da API do aplicativo e mostra as partes do envelope e o JSON descriptografado.
+func executarInspecionar(args []string) error {
+	flags := flag.NewFlagSet("inspecionar", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Uso: gufu inspecionar [opções] entrada\n\nIdentifica se um corpo é uma requisição ou uma resposta criptografada do aplicativo, um erro da API, um JSON ou algo desconhecido,\nmostra o texto criptografado e a senha do envelope, o motivo de uma falha ao descriptografar e o JSON descriptografado.\nUse - para ler da entrada padrão.\n\nOpções:")
+		flags.PrintDefaults()
+	}
+	comoJson := flags.Bool("json", false, "escreve o resultado em JSON")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return errors.New("informe o arquivo de entrada")
+	}
+	entrada, err := lerEntradaCaptura(flags)
+	if err != nil {
+		return err
+	}
+
+	inspecao := gufu.Inspecionar(string(entrada))
+	if *comoJson {
+		codificador := json.NewEncoder(os.Stdout)
+		codificador.SetIndent("", "  ")
+		codificador.SetEscapeHTML(false)
+		if err := codificador.Encode(inspecao); err != nil {
+			return err
+		}
+	} else {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(w, "formato:\t%s\n", inspecao.Formato)
+		if inspecao.Marcador != "" {
+			fmt.Fprintf(w, "marcador:\t%s\n", inspecao.Marcador)
+		}
+		if inspecao.Cifrado != "" {
+			fmt.Fprintf(w, "cifrado:\t%d caracteres em base64\n", len(inspecao.Cifrado))
+			fmt.Fprintf(w, "senha:\t%s\n", inspecao.Senha)
+		}
+		if e := inspecao.ErroMobile; e != nil {
+			fmt.Fprintf(w, "erro da api:\t%d %s: %s (%s)\n", e.Status, e.Error, e.Message, e.Path)
+		}
+		if inspecao.Erro != "" {
+			fmt.Fprintf(w, "erro:\t%s\n", inspecao.Erro)
+		}
+		w.Flush()
+		if inspecao.Texto != "" {
+			var formatado bytes.Buffer
+			if json.Indent(&formatado, []byte(inspecao.Texto), "", "  ") != nil {
+				formatado.WriteString(inspecao.Texto)
+			}
+			fmt.Printf("\n%s\n", formatado.Bytes())
+		}
+	}
+	if inspecao.Err != nil {
+		return errors.New("o envelope não pôde ser descriptografado")
+	}
+	return nil
+}
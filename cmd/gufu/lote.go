@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+
+	"github.com/data-ru/gufu"
+)
+
+// gufu lote [opções] arquivo.csv
+// Lê os ids de uma coluna do CSV (ou da entrada padrão, com "-"), valida todos com gufu.ValidarIdsEmLote
+// e escreve um CSV com o resultado de cada linha na saída padrão.
+func executarLote(args []string) error {
+	flags := flag.NewFlagSet("lote", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Uso: gufu lote [opções] arquivo.csv\n\nValida os ids ufu (ou URLs do valida-ufu) de uma coluna do CSV. Use - para ler da entrada padrão.\n\nOpções:")
+		flags.PrintDefaults()
+	}
+	coluna := flags.Int("coluna", 1, "número da coluna com os ids, começando em 1")
+	cabecalho := flags.Bool("cabecalho", false, "ignora a primeira linha do CSV")
+	separador := flags.String("separador", ",", "separador de colunas do CSV")
+	concorrencia := flags.Int("concorrencia", 4, "quantidade máxima de consultas simultâneas")
+	rps := flags.Float64("rps", 5, "limite de consultas por segundo (0 desativa o limite)")
+	silencioso := flags.Bool("q", false, "não mostra o progresso")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return errors.New("informe um arquivo CSV")
+	}
+	if *coluna < 1 {
+		return errors.New("a coluna deve ser maior que zero")
+	}
+	if len([]rune(*separador)) != 1 {
+		return errors.New("o separador deve ter um caractere")
+	}
+
+	var entrada io.Reader = os.Stdin
+	if nome := flags.Arg(0); nome != "-" {
+		arquivo, err := os.Open(nome)
+		if err != nil {
+			return err
+		}
+		defer arquivo.Close()
+		entrada = arquivo
+	}
+	leitor := csv.NewReader(entrada)
+	leitor.Comma = []rune(*separador)[0]
+	leitor.FieldsPerRecord = -1
+	leitor.TrimLeadingSpace = true
+	linhas, err := leitor.ReadAll()
+	if err != nil {
+		return err
+	}
+	if *cabecalho && len(linhas) > 0 {
+		linhas = linhas[1:]
+	}
+	ids := make([]string, len(linhas))
+	for i, linha := range linhas {
+		if *coluna <= len(linha) {
+			ids[i] = linha[*coluna-1]
+		}
+	}
+
+	ctx, cancelar := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancelar()
+	opcoes := &gufu.OpcoesLote{Concorrencia: *concorrencia, RequisicoesPorSegundo: *rps}
+	if !*silencioso {
+		opcoes.Progresso = func(concluidos, total int) {
+			fmt.Fprintf(os.Stderr, "\r%d/%d ids consultados", concluidos, total)
+			if concluidos == total {
+				fmt.Fprintln(os.Stderr)
+			}
+		}
+	}
+	resultados, errLote := gufu.ValidarIdsEmLote(ctx, ids, opcoes)
+
+	saida := csv.NewWriter(os.Stdout)
+	saida.Write([]string{"entrada", "id", "valida", "nome", "matricula", "situacao", "erro"})
+	for _, r := range resultados {
+		linha := []string{r.Entrada, r.Id, strconv.FormatBool(r.Valida), "", "", "", ""}
+		if r.IdUfu != nil {
+			identidade := r.IdUfu.Identidade()
+			linha[3], linha[4], linha[5] = identidade.Nome, identidade.Matricula, identidade.Situacao.String()
+		}
+		if r.Err != nil {
+			linha[6] = r.Err.Error()
+		}
+		saida.Write(linha)
+	}
+	saida.Flush()
+	if err := saida.Error(); err != nil {
+		return err
+	}
+	return errLote
+}
@@ -0,0 +1,64 @@
+// Comando gufu: ferramentas de linha de comando para as APIs da UFU, construídas sobre a biblioteca gufu.
+//
+// Uso:
+//
+//	gufu <comando> [opções] [argumentos]
+//
+// Use "gufu <comando> -h" para ver as opções de cada comando.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// Um subcomando do gufu. executar recebe os argumentos que vêm depois do nome do comando.
+type comando struct {
+	descricao string
+	executar  func(args []string) error
+}
+
+var comandos = map[string]comando{
+	"criptografar":    {"Criptografa de novo os corpos de uma captura HAR ou um JSON avulso", executarCriptografar},
+	"descriptografar": {"Descriptografa os corpos de uma captura HAR ou um envelope avulso", executarDescriptografar},
+	"inspecionar":     {"Identifica o formato de um corpo do aplicativo e o descriptografa", executarInspecionar},
+	"lote":            {"Valida em lote os ids ufu de um arquivo CSV", executarLote},
+	"proxy":           {"Captura e descriptografa o tráfego do aplicativo em um arquivo HAR", executarProxy},
+	"saude":           {"Verifica se os sistemas da UFU estão respondendo", executarSaude},
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		uso()
+		os.Exit(2)
+	}
+	cmd, ok := comandos[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "gufu: comando desconhecido %q\n", os.Args[1])
+		uso()
+		os.Exit(2)
+	}
+	err := cmd.executar(os.Args[2:])
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "gufu:", err)
+		os.Exit(1)
+	}
+}
+
+func uso() {
+	fmt.Fprintln(os.Stderr, "Uso: gufu <comando> [opções] [argumentos]\n\nComandos:")
+	nomes := make([]string, 0, len(comandos))
+	for nome := range comandos {
+		nomes = append(nomes, nome)
+	}
+	sort.Strings(nomes)
+	for _, nome := range nomes {
+		fmt.Fprintf(os.Stderr, "  %-16s %s\n", nome, comandos[nome].descricao)
+	}
+}
@@ -0,0 +1,390 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/data-ru/gufu"
+)
+
+// gufu proxy [opções]
+// Inicia um proxy HTTP(S) local para capturar o tráfego do aplicativo. As conexões HTTPS para os hosts interceptados
+// são abertas com certificados assinados por uma autoridade certificadora local, e os corpos das requisições
+// (requestParams) e das respostas da API do aplicativo são descriptografados e registrados em um arquivo HAR.
+// Cada entrada é acrescentada ao arquivo assim que a resposta chega, sem guardar as anteriores na memória.
+func executarProxy(args []string) error {
+	flags := flag.NewFlagSet("proxy", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Uso: gufu proxy [opções]\n\nInicia um proxy HTTP(S) que descriptografa o tráfego do aplicativo da UFU e o registra em um arquivo HAR.\nConfigure o aparelho para usar o proxy e instale o certificado da autoridade local como confiável.\n\nOpções:")
+		flags.PrintDefaults()
+	}
+	endereco := flags.String("endereco", "127.0.0.1:8888", "endereço em que o proxy escuta (use 0.0.0.0:8888 para aceitar conexões de outros aparelhos)")
+	arquivoHar := flags.String("har", "gufu.har", "arquivo HAR em que o tráfego é registrado")
+	dirCA := flags.String("ca", diretorioPadraoCA(), "diretório da autoridade certificadora local, criada se não existir")
+	interceptar := flags.String("interceptar", "www.sistemas.ufu.br", "hosts HTTPS interceptados, separados por vírgula (* para todos). Os demais passam por um túnel, sem registro")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	ca, caminhoCA, err := carregarOuCriarCA(*dirCA)
+	if err != nil {
+		return fmt.Errorf("falha ao carregar a autoridade certificadora: %w", err)
+	}
+	har, err := criarArquivoHAR(*arquivoHar)
+	if err != nil {
+		return fmt.Errorf("falha ao criar %s: %w", *arquivoHar, err)
+	}
+	defer har.Close()
+	transporte := http.DefaultTransport.(*http.Transport).Clone()
+	transporte.Proxy = nil
+	transporte.DisableCompression = true
+	p := &proxy{
+		ca:         ca,
+		hosts:      map[string]bool{},
+		har:        har,
+		transporte: transporte,
+	}
+	for _, host := range strings.Split(*interceptar, ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			p.hosts[strings.ToLower(host)] = true
+		}
+	}
+
+	//Com Ctrl+C, espera as requisições em andamento serem registradas antes de fechar o arquivo
+	ctx, cancelar := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancelar()
+	servidor := &http.Server{Addr: *endereco, Handler: p}
+	go func() {
+		<-ctx.Done()
+		encerrar, cancelarEncerramento := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancelarEncerramento()
+		servidor.Shutdown(encerrar)
+	}()
+
+	fmt.Fprintf(os.Stderr, "proxy em http://%s, registrando o tráfego em %s\ninstale %s no aparelho como autoridade certificadora confiável\n", *endereco, *arquivoHar, caminhoCA)
+	if err := servidor.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "%d requisições registradas em %s\n", har.Entradas(), *arquivoHar)
+	return nil
+}
+
+type proxy struct {
+	ca         *autoridade
+	hosts      map[string]bool
+	transporte *http.Transport
+	har        *arquivoHAR
+}
+
+// Cabeçalhos que valem apenas para uma conexão e não são repassados
+var cabecalhosDeConexao = []string{"Connection", "Proxy-Connection", "Keep-Alive", "Proxy-Authenticate", "Proxy-Authorization", "Te", "Trailer", "Transfer-Encoding", "Upgrade"}
+
+func (p *proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodConnect {
+		p.conectar(w, r)
+		return
+	}
+	if !r.URL.IsAbs() {
+		http.Error(w, "gufu proxy: configure este endereço como proxy HTTP", http.StatusBadRequest)
+		return
+	}
+	resposta, err := p.encaminhar(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resposta.Body.Close()
+	for nome, valores := range resposta.Header {
+		w.Header()[nome] = valores
+	}
+	w.WriteHeader(resposta.StatusCode)
+	io.Copy(w, resposta.Body)
+}
+
+func (p *proxy) interceptar(host string) bool {
+	return p.hosts["*"] || p.hosts[strings.ToLower(host)]
+}
+
+// Responde a um CONNECT: intercepta a conexão TLS se o host estiver em -interceptar ou abre um túnel caso contrário
+func (p *proxy) conectar(w http.ResponseWriter, r *http.Request) {
+	host, _, err := net.SplitHostPort(r.Host)
+	if err != nil {
+		host = r.Host
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "gufu proxy: conexão não suportada", http.StatusInternalServerError)
+		return
+	}
+
+	if !p.interceptar(host) {
+		destino, err := net.DialTimeout("tcp", r.Host, 10*time.Second)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		conexao, _, err := hijacker.Hijack()
+		if err != nil {
+			destino.Close()
+			return
+		}
+		conexao.Write([]byte("HTTP/1.1 200 Connection Established\r\n\r\n"))
+		go func() {
+			io.Copy(destino, conexao)
+			destino.Close()
+		}()
+		io.Copy(conexao, destino)
+		conexao.Close()
+		return
+	}
+
+	conexao, _, err := hijacker.Hijack()
+	if err != nil {
+		return
+	}
+	conexao.Write([]byte("HTTP/1.1 200 Connection Established\r\n\r\n"))
+	conexaoTLS := tls.Server(conexao, &tls.Config{
+		NextProtos: []string{"http/1.1"},
+		GetCertificate: func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+			if hello.ServerName != "" {
+				return p.ca.certificadoPara(hello.ServerName)
+			}
+			return p.ca.certificadoPara(host)
+		},
+	})
+	defer conexaoTLS.Close()
+	if err := conexaoTLS.Handshake(); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: falha no TLS (o certificado da autoridade está instalado?): %v\n", r.Host, err)
+		return
+	}
+
+	leitor := bufio.NewReader(conexaoTLS)
+	for {
+		requisicao, err := http.ReadRequest(leitor)
+		if err != nil {
+			return
+		}
+		requisicao.URL.Scheme = "https"
+		requisicao.URL.Host = requisicao.Host
+		if requisicao.URL.Host == "" {
+			requisicao.URL.Host = r.Host
+		}
+		resposta, err := p.encaminhar(requisicao)
+		if err != nil {
+			resposta = &http.Response{
+				StatusCode:    http.StatusBadGateway,
+				ProtoMajor:    1,
+				ProtoMinor:    1,
+				Header:        http.Header{},
+				Body:          io.NopCloser(strings.NewReader(err.Error())),
+				ContentLength: int64(len(err.Error())),
+			}
+		}
+		resposta.Close = requisicao.Close
+		err = resposta.Write(conexaoTLS)
+		resposta.Body.Close()
+		if err != nil || requisicao.Close {
+			return
+		}
+	}
+}
+
+// Envia a requisição ao destino e registra a requisição e a resposta no HAR. O corpo da resposta é lido
+// inteiro para ser registrado, e a resposta retornada pode ser repassada ao aparelho.
+func (p *proxy) encaminhar(r *http.Request) (*http.Response, error) {
+	inicio := time.Now()
+	corpo, err := io.ReadAll(r.Body)
+	r.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	saida, err := http.NewRequestWithContext(r.Context(), r.Method, r.URL.String(), bytes.NewReader(corpo))
+	if err != nil {
+		return nil, err
+	}
+	saida.Header = r.Header.Clone()
+	for _, nome := range cabecalhosDeConexao {
+		saida.Header.Del(nome)
+	}
+	//Pede a resposta sem compressão para poder descriptografar e registrar o corpo
+	saida.Header.Del("Accept-Encoding")
+
+	resposta, err := p.transporte.RoundTrip(saida)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s %s: %v\n", r.Method, r.URL, err)
+		return nil, err
+	}
+	corpoResposta, err := io.ReadAll(resposta.Body)
+	resposta.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	duracao := time.Since(inicio)
+	for _, nome := range cabecalhosDeConexao {
+		resposta.Header.Del(nome)
+	}
+	resposta.Body = io.NopCloser(bytes.NewReader(corpoResposta))
+	resposta.ContentLength = int64(len(corpoResposta))
+	resposta.TransferEncoding = nil
+
+	entrada := entradaHAR(r, corpo, resposta, corpoResposta, inicio, duracao)
+	descricao := ""
+	if (entrada.Request.PostData != nil && entrada.Request.PostData.Comment != "") || entrada.Response.Content.Comment != "" {
+		descricao = " (" + gufu.ComentarioHARDescriptografado + ")"
+	}
+	fmt.Fprintf(os.Stderr, "%s %s %d %v%s\n", r.Method, r.URL, resposta.StatusCode, duracao.Round(time.Millisecond), descricao)
+	if err := p.har.Acrescentar(entrada); err != nil {
+		fmt.Fprintf(os.Stderr, "falha ao escrever %s: %v\n", p.har.Name(), err)
+	}
+	return resposta, nil
+}
+
+// Arquivo HAR escrito aos poucos: cada entrada é acrescentada antes do fechamento da lista de entradas, que é
+// reescrito em seguida. Assim o arquivo é um HAR completo depois de cada entrada, mesmo se o proxy for interrompido,
+// sem reescrever as entradas anteriores nem guardá-las na memória.
+type arquivoHAR struct {
+	*os.File
+
+	mu         sync.Mutex
+	fim        int64  //Posição logo depois da última entrada, onde começa o fechamento
+	fechamento []byte //O que vem depois da lista de entradas
+	entradas   int
+}
+
+// Cria (ou substitui) o arquivo com um HAR sem entradas
+func criarArquivoHAR(caminho string) (*arquivoHAR, error) {
+	vazio, err := json.MarshalIndent(gufu.NovoHAR(), "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	//A lista de entradas vazia é o último [] do documento: o que vem antes é escrito uma vez e o resto, depois de cada entrada
+	i := bytes.LastIndex(vazio, []byte("[]"))
+	arquivo, err := os.Create(caminho)
+	if err != nil {
+		return nil, err
+	}
+	h := &arquivoHAR{File: arquivo, fim: int64(i + 1), fechamento: append(vazio[i+1:], '\n')}
+	if _, err := arquivo.Write(vazio[:i+1]); err != nil {
+		arquivo.Close()
+		return nil, err
+	}
+	if _, err := arquivo.Write(h.fechamento); err != nil {
+		arquivo.Close()
+		return nil, err
+	}
+	return h, nil
+}
+
+// Escreve a entrada no lugar do fechamento e escreve o fechamento de novo depois dela
+func (h *arquivoHAR) Acrescentar(entrada gufu.EntradaHAR) error {
+	dados, err := json.MarshalIndent(entrada, "      ", "  ")
+	if err != nil {
+		return err
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	separador := ",\n      "
+	if h.entradas == 0 {
+		separador = "\n      "
+	}
+	trecho := append([]byte(separador), dados...)
+	if _, err := h.WriteAt(append(append(trecho, "\n    "...), h.fechamento...), h.fim); err != nil {
+		return err
+	}
+	h.fim += int64(len(trecho))
+	h.entradas++
+	return nil
+}
+
+// Quantidade de entradas escritas
+func (h *arquivoHAR) Entradas() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.entradas
+}
+
+// Fecha o arquivo depois da entrada que estiver sendo escrita
+func (h *arquivoHAR) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.File.Close()
+}
+
+func entradaHAR(r *http.Request, corpo []byte, resposta *http.Response, corpoResposta []byte, inicio time.Time, duracao time.Duration) gufu.EntradaHAR {
+	ms := float64(duracao.Microseconds()) / 1000
+	entrada := gufu.EntradaHAR{
+		StartedDateTime: inicio,
+		Time:            ms,
+		Request: gufu.RequisicaoHAR{
+			Method:      r.Method,
+			URL:         r.URL.String(),
+			HTTPVersion: r.Proto,
+			Cookies:     []gufu.CookieHAR{},
+			Headers:     cabecalhosHAR(r.Header),
+			QueryString: []gufu.CabecalhoHAR{},
+			HeadersSize: -1,
+			BodySize:    len(corpo),
+		},
+		Response: gufu.RespostaHAR{
+			Status:      resposta.StatusCode,
+			StatusText:  http.StatusText(resposta.StatusCode),
+			HTTPVersion: resposta.Proto,
+			Cookies:     []gufu.CookieHAR{},
+			Headers:     cabecalhosHAR(resposta.Header),
+			Content:     gufu.ConteudoHAR{Size: len(corpoResposta), MimeType: resposta.Header.Get("Content-Type")},
+			RedirectURL: resposta.Header.Get("Location"),
+			HeadersSize: -1,
+			BodySize:    len(corpoResposta),
+		},
+		Timings: gufu.TemposHAR{Send: 0, Wait: ms, Receive: 0},
+	}
+	for _, c := range r.Cookies() {
+		entrada.Request.Cookies = append(entrada.Request.Cookies, gufu.CookieHAR{Name: c.Name, Value: c.Value})
+	}
+	for _, c := range resposta.Cookies() {
+		entrada.Response.Cookies = append(entrada.Response.Cookies, gufu.CookieHAR{Name: c.Name, Value: c.Value})
+	}
+	for nome, valores := range r.URL.Query() {
+		for _, valor := range valores {
+			entrada.Request.QueryString = append(entrada.Request.QueryString, gufu.CabecalhoHAR{Name: nome, Value: valor})
+		}
+	}
+
+	if len(corpo) > 0 {
+		entrada.Request.PostData = &gufu.DadosPostHAR{MimeType: r.Header.Get("Content-Type"), Text: string(corpo)}
+	}
+	if utf8.Valid(corpoResposta) {
+		entrada.Response.Content.Text = string(corpoResposta)
+	} else {
+		entrada.Response.Content.Text = base64.StdEncoding.EncodeToString(corpoResposta)
+		entrada.Response.Content.Encoding = "base64"
+	}
+	gufu.DescriptografarEntradaHAR(&entrada)
+	return entrada
+}
+
+func cabecalhosHAR(cabecalhos http.Header) []gufu.CabecalhoHAR {
+	har := []gufu.CabecalhoHAR{}
+	for nome, valores := range cabecalhos {
+		for _, valor := range valores {
+			har = append(har, gufu.CabecalhoHAR{Name: nome, Value: valor})
+		}
+	}
+	return har
+}
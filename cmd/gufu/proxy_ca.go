@@ -0,0 +1,178 @@
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	arquivoCertificadoCA = "gufu-ca.pem"
+	arquivoChaveCA       = "gufu-ca-chave.pem"
+)
+
+// Autoridade certificadora local usada pelo proxy para gerar, para cada host interceptado, um certificado
+// aceito pelo aparelho em que o certificado da autoridade foi instalado.
+type autoridade struct {
+	certificado *x509.Certificate
+	chave       crypto.Signer
+	chaveHosts  *ecdsa.PrivateKey //Chave usada por todos os certificados dos hosts
+
+	mu    sync.Mutex
+	hosts map[string]*tls.Certificate
+}
+
+// Diretório padrão da autoridade, dentro do diretório de configuração do usuário.
+func diretorioPadraoCA() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "gufu-ca"
+	}
+	return filepath.Join(dir, "gufu")
+}
+
+// Carrega a autoridade de dir ou, se ela ainda não existir, cria uma nova e a salva em dir.
+// Retorna também o caminho do certificado, que precisa ser instalado no aparelho.
+func carregarOuCriarCA(dir string) (*autoridade, string, error) {
+	caminhoCertificado, caminhoChave := filepath.Join(dir, arquivoCertificadoCA), filepath.Join(dir, arquivoChaveCA)
+	certificado, chave, err := carregarCA(caminhoCertificado, caminhoChave)
+	if errors.Is(err, os.ErrNotExist) {
+		certificado, chave, err = criarCA(dir, caminhoCertificado, caminhoChave)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	chaveHosts, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, "", err
+	}
+	return &autoridade{certificado: certificado, chave: chave, chaveHosts: chaveHosts, hosts: map[string]*tls.Certificate{}}, caminhoCertificado, nil
+}
+
+func carregarCA(caminhoCertificado, caminhoChave string) (*x509.Certificate, crypto.Signer, error) {
+	pemCertificado, err := os.ReadFile(caminhoCertificado)
+	if err != nil {
+		return nil, nil, err
+	}
+	pemChave, err := os.ReadFile(caminhoChave)
+	if err != nil {
+		return nil, nil, err
+	}
+	bloco, _ := pem.Decode(pemCertificado)
+	if bloco == nil {
+		return nil, nil, fmt.Errorf("%s não contém um certificado PEM", caminhoCertificado)
+	}
+	certificado, err := x509.ParseCertificate(bloco.Bytes)
+	if err != nil {
+		return nil, nil, err
+	}
+	bloco, _ = pem.Decode(pemChave)
+	if bloco == nil {
+		return nil, nil, fmt.Errorf("%s não contém uma chave PEM", caminhoChave)
+	}
+	chave, err := x509.ParsePKCS8PrivateKey(bloco.Bytes)
+	if err != nil {
+		return nil, nil, err
+	}
+	assinador, ok := chave.(crypto.Signer)
+	if !ok {
+		return nil, nil, fmt.Errorf("tipo de chave não suportado em %s", caminhoChave)
+	}
+	return certificado, assinador, nil
+}
+
+func criarCA(dir, caminhoCertificado, caminhoChave string) (*x509.Certificate, crypto.Signer, error) {
+	chave, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	serial, err := numeroDeSerie()
+	if err != nil {
+		return nil, nil, err
+	}
+	agora := time.Now()
+	modelo := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "gufu proxy CA", Organization: []string{"gufu"}},
+		NotBefore:             agora.Add(-time.Hour),
+		NotAfter:              agora.AddDate(10, 0, 0),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, modelo, modelo, &chave.PublicKey, chave)
+	if err != nil {
+		return nil, nil, err
+	}
+	certificado, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, err
+	}
+	derChave, err := x509.MarshalPKCS8PrivateKey(chave)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, nil, err
+	}
+	if err := os.WriteFile(caminhoChave, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: derChave}), 0o600); err != nil {
+		return nil, nil, err
+	}
+	if err := os.WriteFile(caminhoCertificado, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o644); err != nil {
+		return nil, nil, err
+	}
+	return certificado, chave, nil
+}
+
+// Retorna o certificado do host assinado pela autoridade, gerando e guardando na primeira vez.
+func (a *autoridade) certificadoPara(host string) (*tls.Certificate, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if certificado, ok := a.hosts[host]; ok {
+		return certificado, nil
+	}
+	serial, err := numeroDeSerie()
+	if err != nil {
+		return nil, err
+	}
+	agora := time.Now()
+	modelo := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: host, Organization: []string{"gufu"}},
+		NotBefore:    agora.Add(-time.Hour),
+		NotAfter:     agora.AddDate(0, 0, 390), //Os aparelhos da Apple recusam certificados com mais de 398 dias
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		modelo.IPAddresses = []net.IP{ip}
+	} else {
+		modelo.DNSNames = []string{host}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, modelo, a.certificado, &a.chaveHosts.PublicKey, a.chave)
+	if err != nil {
+		return nil, err
+	}
+	certificado := &tls.Certificate{Certificate: [][]byte{der, a.certificado.Raw}, PrivateKey: a.chaveHosts}
+	a.hosts[host] = certificado
+	return certificado, nil
+}
+
+func numeroDeSerie() (*big.Int, error) {
+	return rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/data-ru/gufu"
+)
+
+// gufu saude [opções]
+// Verifica se o SSO, a API do aplicativo e a API de validação estão respondendo. Com -servir, inicia um servidor HTTP
+// que responde à verificação em /saude, para ser usado por dashboards e monitores.
+func executarSaude(args []string) error {
+	flags := flag.NewFlagSet("saude", flag.ContinueOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Uso: gufu saude [opções]\n\nVerifica se os sistemas da UFU estão respondendo e se o envelope de criptografia do aplicativo ainda funciona.\n\nOpções:")
+		flags.PrintDefaults()
+	}
+	comoJson := flags.Bool("json", false, "escreve o relatório em JSON")
+	timeout := flags.Duration("timeout", gufu.TimeoutVerificacao, "tempo máximo de cada verificação")
+	servir := flags.String("servir", "", "endereço (Ex: :8080) de um servidor HTTP que responde à verificação em /saude")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	gufu.TimeoutVerificacao = *timeout
+
+	if *servir != "" {
+		http.Handle("/saude", gufu.HandlerSaude())
+		fmt.Fprintf(os.Stderr, "respondendo em http://%s/saude\n", *servir)
+		return http.ListenAndServe(*servir, nil)
+	}
+
+	relatorio := gufu.Verificar(context.Background())
+	if *comoJson {
+		codificador := json.NewEncoder(os.Stdout)
+		codificador.SetIndent("", "  ")
+		if err := codificador.Encode(relatorio); err != nil {
+			return err
+		}
+	} else {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "SERVIÇO\tESTADO\tSTATUS\tLATÊNCIA\tENVELOPE\tERRO")
+		for _, s := range relatorio.Servicos {
+			estado, status, envelope := "ok", "-", "-"
+			if !s.Ok() {
+				estado = "falha"
+			}
+			if s.Status != 0 {
+				status = fmt.Sprint(s.Status)
+			}
+			if s.EnvelopeValido != nil {
+				envelope = map[bool]string{true: "ok", false: "falha"}[*s.EnvelopeValido]
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%v\t%s\t%s\n", s.Servico, estado, status, s.Latencia.Round(time.Millisecond), envelope, s.Erro)
+		}
+		w.Flush()
+	}
+	if !relatorio.Saudavel {
+		return errors.New("há serviços com falha")
+	}
+	return nil
+}
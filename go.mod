@@ -6,4 +6,7 @@ require (
 	github.com/joho/godotenv v1.5.1
 	github.com/paemuri/brdoc v1.1.2
 	golang.org/x/crypto v0.24.0
+	golang.org/x/image v0.18.0
 )
+
+require golang.org/x/text v0.16.0 // indirect
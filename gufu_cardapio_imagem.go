@@ -0,0 +1,318 @@
+package gufu
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+var (
+	ErrCardapioNaoCabe = errors.New("o cardápio não cabe no tamanho de imagem escolhido")
+)
+
+// OpcoesImagemCardapio contém as configurações usadas para renderizar um Cardapio como imagem.
+// Os campos com valor zero usam os valores padrão.
+type OpcoesImagemCardapio struct {
+	Largura      int         //Largura da imagem em pixels. Padrão: 1080
+	Altura       int         //Altura da imagem em pixels. Padrão: 1350 (formato retrato do Instagram)
+	Campus       string      //Chave do campus em Campi (Ex: "sm"). Se vazio, o campus é inferido a partir de Cardapio.Local
+	TamanhoFonte float64     //Tamanho da fonte do texto em pixels. Se zero, é escolhido o maior tamanho que faz o cardápio caber na imagem
+	CorFundo     color.Color //Cor de fundo. Padrão: branco
+	CorTexto     color.Color //Cor do texto. Padrão: cinza escuro
+	CorDestaque  color.Color //Cor do cabeçalho e dos títulos das seções. Padrão: azul da UFU
+}
+
+var (
+	fontesCardapio     struct{ regular, negrito *opentype.Font }
+	fontesCardapioOnce sync.Once
+	fontesCardapioErr  error
+)
+
+// Carrega as fontes embutidas (Go Regular e Go Bold) apenas uma vez.
+func carregarFontesCardapio() error {
+	fontesCardapioOnce.Do(func() {
+		fontesCardapio.regular, fontesCardapioErr = opentype.Parse(goregular.TTF)
+		if fontesCardapioErr != nil {
+			return
+		}
+		fontesCardapio.negrito, fontesCardapioErr = opentype.Parse(gobold.TTF)
+	})
+	return fontesCardapioErr
+}
+
+// Preenche os campos vazios das opções com os valores padrão.
+func (o *OpcoesImagemCardapio) comPadroes() OpcoesImagemCardapio {
+	var opcoes OpcoesImagemCardapio
+	if o != nil {
+		opcoes = *o
+	}
+	if opcoes.Largura <= 0 {
+		opcoes.Largura = 1080
+	}
+	if opcoes.Altura <= 0 {
+		opcoes.Altura = 1350
+	}
+	if opcoes.CorFundo == nil {
+		opcoes.CorFundo = color.White
+	}
+	if opcoes.CorTexto == nil {
+		opcoes.CorTexto = color.RGBA{R: 0x33, G: 0x33, B: 0x33, A: 0xff}
+	}
+	if opcoes.CorDestaque == nil {
+		opcoes.CorDestaque = color.RGBA{R: 0x00, G: 0x3c, B: 0x78, A: 0xff}
+	}
+	return opcoes
+}
+
+// NomeCampus retorna o nome do campus do cardápio, de acordo com Campi.
+// Se nenhum campus de Campi aparecer em Local, retorna o próprio Local.
+func (c *Cardapio) NomeCampus() string {
+	chaves := make([]string, 0, len(Campi))
+	for k := range Campi {
+		chaves = append(chaves, k)
+	}
+	sort.Strings(chaves)
+	for _, k := range chaves {
+		if strings.Contains(c.Local, Campi[k].Nome) {
+			return Campi[k].Nome
+		}
+	}
+	return c.Local
+}
+
+// Um item do cardápio, como "Arroz: Integral".
+type itemCardapio struct {
+	rotulo, valor string
+}
+
+// Uma seção do cardápio (almoço ou jantar) com os itens não vazios.
+type secaoCardapio struct {
+	titulo string
+	itens  []itemCardapio
+}
+
+// Monta as seções do cardápio, ignorando os itens vazios e as seções sem itens.
+func (c *Cardapio) secoes() []secaoCardapio {
+	todas := []secaoCardapio{
+		{titulo: "Almoço", itens: []itemCardapio{
+			{"Principal", c.PrincipalAlmoco},
+			{"Vegetariano", c.VegetarianoAlmoco},
+			{"Arroz", c.ArrozAlmoco},
+			{"Feijão", c.FeijaoAlmoco},
+			{"Guarnição", c.GuarnicaoAlmoco},
+			{"Salada", c.SaladaAlmoco},
+			{"Sobremesa", c.SobremesaAlmoco},
+			{"Suco", c.SucoAlmoco},
+		}},
+		{titulo: "Jantar", itens: []itemCardapio{
+			{"Principal", c.PrincipalJantar},
+			{"Vegetariano", c.VegetarianoJantar},
+			{"Arroz", c.ArrozJantar},
+			{"Feijão", c.FeijaoJantar},
+			{"Guarnição", c.GuarnicaoJantar},
+			{"Salada", c.SaladaJantar},
+			{"Sobremesa", c.SobremesaJantar},
+			{"Suco", c.SucoJantar},
+		}},
+	}
+
+	var secoes []secaoCardapio
+	for _, s := range todas {
+		var itens []itemCardapio
+		for _, i := range s.itens {
+			i.valor = strings.TrimSpace(i.valor)
+			if i.valor != "" {
+				itens = append(itens, i)
+			}
+		}
+		if len(itens) > 0 {
+			secoes = append(secoes, secaoCardapio{titulo: s.titulo, itens: itens})
+		}
+	}
+	return secoes
+}
+
+// Um pedaço de texto posicionado na imagem, já com a fonte e a cor definidas.
+type trechoCardapio struct {
+	texto string
+	face  font.Face
+	cor   color.Color
+	x, y  int //Posição da linha de base do texto
+}
+
+// Quebra o texto em linhas que caibam em largura, sem quebrar palavras.
+// Uma palavra maior que a largura fica sozinha em sua linha.
+func quebrarTexto(face font.Face, texto string, largura int) []string {
+	palavras := strings.Fields(texto)
+	if len(palavras) == 0 {
+		return nil
+	}
+	limite := fixed.I(largura)
+	var linhas []string
+	atual := palavras[0]
+	for _, p := range palavras[1:] {
+		if font.MeasureString(face, atual+" "+p) <= limite {
+			atual += " " + p
+			continue
+		}
+		linhas = append(linhas, atual)
+		atual = p
+	}
+	return append(linhas, atual)
+}
+
+// Calcula a posição de todos os trechos de texto para o tamanho de fonte informado.
+// Retorna os trechos, a altura do cabeçalho e se o conteúdo coube na imagem.
+func (c *Cardapio) diagramar(opcoes OpcoesImagemCardapio, tamanho float64) ([]trechoCardapio, int, bool, error) {
+	novaFace := func(f *opentype.Font, tamanho float64) (font.Face, error) {
+		return opentype.NewFace(f, &opentype.FaceOptions{Size: tamanho, DPI: 72, Hinting: font.HintingFull})
+	}
+	faceTitulo, err := novaFace(fontesCardapio.negrito, tamanho*2)
+	if err != nil {
+		return nil, 0, false, err
+	}
+	faceSubtitulo, err := novaFace(fontesCardapio.regular, tamanho*1.2)
+	if err != nil {
+		return nil, 0, false, err
+	}
+	faceSecao, err := novaFace(fontesCardapio.negrito, tamanho*1.5)
+	if err != nil {
+		return nil, 0, false, err
+	}
+	faceRotulo, err := novaFace(fontesCardapio.negrito, tamanho)
+	if err != nil {
+		return nil, 0, false, err
+	}
+	faceTexto, err := novaFace(fontesCardapio.regular, tamanho)
+	if err != nil {
+		return nil, 0, false, err
+	}
+
+	margem := opcoes.Largura / 18
+	larguraUtil := opcoes.Largura - 2*margem
+	alturaLinha := func(f font.Face) int {
+		return f.Metrics().Height.Ceil()
+	}
+	branco := color.White
+
+	var trechos []trechoCardapio
+	y := margem
+
+	//Cabeçalho: título, campus e data, em texto claro sobre a cor de destaque
+	for _, l := range quebrarTexto(faceTitulo, "Cardápio RU", larguraUtil) {
+		y += alturaLinha(faceTitulo)
+		trechos = append(trechos, trechoCardapio{l, faceTitulo, branco, margem, y})
+	}
+	subtitulo := c.NomeCampus()
+	if opcoes.Campus != "" {
+		campus, ok := Campi[opcoes.Campus]
+		if !ok {
+			return nil, 0, false, ErrCampusInvalido
+		}
+		subtitulo = campus.Nome
+	}
+	if c.Data != "" {
+		subtitulo = strings.TrimSpace(subtitulo + " - " + c.Data)
+	}
+	for _, l := range quebrarTexto(faceSubtitulo, subtitulo, larguraUtil) {
+		y += alturaLinha(faceSubtitulo)
+		trechos = append(trechos, trechoCardapio{l, faceSubtitulo, branco, margem, y})
+	}
+	y += margem / 2
+	alturaCabecalho := y
+
+	for _, s := range c.secoes() {
+		y += margem / 2
+		y += alturaLinha(faceSecao)
+		trechos = append(trechos, trechoCardapio{s.titulo, faceSecao, opcoes.CorDestaque, margem, y})
+		y += alturaLinha(faceTexto) / 4
+
+		for _, i := range s.itens {
+			rotulo := i.rotulo + ": "
+			recuo := font.MeasureString(faceRotulo, rotulo).Ceil()
+			if recuo > larguraUtil/2 {
+				recuo = larguraUtil / 2
+			}
+			y += alturaLinha(faceTexto)
+			trechos = append(trechos, trechoCardapio{rotulo, faceRotulo, opcoes.CorDestaque, margem, y})
+			for n, l := range quebrarTexto(faceTexto, i.valor, larguraUtil-recuo) {
+				if n > 0 {
+					y += alturaLinha(faceTexto)
+				}
+				trechos = append(trechos, trechoCardapio{l, faceTexto, opcoes.CorTexto, margem + recuo, y})
+			}
+		}
+	}
+
+	return trechos, alturaCabecalho, y+margem <= opcoes.Altura, nil
+}
+
+// RenderizarImagem desenha o cardápio em uma imagem, com o cabeçalho (campus e data) e as seções de almoço e jantar.
+// As fontes são embutidas na biblioteca, então não é necessário nenhum navegador ou fonte instalada no sistema.
+// Se opcoes for nil, são usados os valores padrão. Retorna ErrCardapioNaoCabe se o texto não couber na imagem.
+func (c *Cardapio) RenderizarImagem(opcoes *OpcoesImagemCardapio) (*image.RGBA, error) {
+	if err := carregarFontesCardapio(); err != nil {
+		return nil, err
+	}
+	o := opcoes.comPadroes()
+
+	var (
+		trechos         []trechoCardapio
+		alturaCabecalho int
+		coube           bool
+		err             error
+	)
+	if o.TamanhoFonte > 0 {
+		trechos, alturaCabecalho, coube, err = c.diagramar(o, o.TamanhoFonte)
+	} else {
+		//Procura o maior tamanho de fonte que faz o cardápio caber na imagem
+		for tamanho := float64(o.Largura) / 20; tamanho >= 8; tamanho-- {
+			trechos, alturaCabecalho, coube, err = c.diagramar(o, tamanho)
+			if err != nil || coube {
+				break
+			}
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !coube {
+		return nil, ErrCardapioNaoCabe
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, o.Largura, o.Altura))
+	draw.Draw(img, img.Bounds(), image.NewUniform(o.CorFundo), image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(0, 0, o.Largura, alturaCabecalho), image.NewUniform(o.CorDestaque), image.Point{}, draw.Src)
+
+	for _, t := range trechos {
+		d := font.Drawer{
+			Dst:  img,
+			Src:  image.NewUniform(t.cor),
+			Face: t.face,
+			Dot:  fixed.P(t.x, t.y),
+		}
+		d.DrawString(t.texto)
+	}
+	return img, nil
+}
+
+// RenderizarPNG desenha o cardápio (veja RenderizarImagem) e escreve a imagem em w no formato PNG.
+func (c *Cardapio) RenderizarPNG(w io.Writer, opcoes *OpcoesImagemCardapio) error {
+	img, err := c.RenderizarImagem(opcoes)
+	if err != nil {
+		return err
+	}
+	return png.Encode(w, img)
+}
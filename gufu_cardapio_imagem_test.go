@@ -0,0 +1,70 @@
+package gufu
+
+import (
+	"bytes"
+	"errors"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+var cardapioExemplo = Cardapio{
+	Titulo:            "2024/12/16 - Cardápio Restaurante Universitário - Santa Mônica",
+	Local:             "Restaurante Universitário - Santa Mônica",
+	PrincipalAlmoco:   "Frango assado com molho de ervas",
+	VegetarianoAlmoco: "Grão-de-bico ao curry",
+	ArrozAlmoco:       "Arroz branco e integral",
+	FeijaoAlmoco:      "Feijão carioca",
+	GuarnicaoAlmoco:   "Farofa de cenoura",
+	SaladaAlmoco:      "Alface, tomate e pepino",
+	SobremesaAlmoco:   "Banana",
+	SucoAlmoco:        "Maracujá",
+	Data:              "16/12/2024",
+	PrincipalJantar:   "Carne moída com batata",
+	VegetarianoJantar: "Omelete de espinafre",
+	ArrozJantar:       "Arroz branco",
+	FeijaoJantar:      "Feijão preto",
+}
+
+func TestNomeCampusCardapio(t *testing.T) {
+	if n := cardapioExemplo.NomeCampus(); n != "Santa Mônica" {
+		t.Fatalf("campus esperado Santa Mônica, obtido %q", n)
+	}
+	c := Cardapio{Local: "Restaurante Desconhecido"}
+	if n := c.NomeCampus(); n != c.Local {
+		t.Fatalf("campus esperado %q, obtido %q", c.Local, n)
+	}
+}
+
+func TestRenderizarCardapioPNG(t *testing.T) {
+	var buf bytes.Buffer
+	destaque := color.RGBA{R: 0xaa, G: 0x11, B: 0x22, A: 0xff}
+	err := cardapioExemplo.RenderizarPNG(&buf, &OpcoesImagemCardapio{Largura: 800, Altura: 1000, CorDestaque: destaque})
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := img.Bounds(); b.Dx() != 800 || b.Dy() != 1000 {
+		t.Fatalf("tamanho inesperado: %v", b)
+	}
+	if c := color.RGBAModel.Convert(img.At(1, 1)); c != destaque {
+		t.Fatalf("o cabeçalho deveria usar a cor de destaque, obtido %v", c)
+	}
+	if c := color.RGBAModel.Convert(img.At(799, 999)); c != (color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}) {
+		t.Fatalf("o fundo deveria ser branco, obtido %v", c)
+	}
+}
+
+func TestRenderizarCardapioNaoCabe(t *testing.T) {
+	_, err := cardapioExemplo.RenderizarImagem(&OpcoesImagemCardapio{Largura: 300, Altura: 200})
+	if !errors.Is(err, ErrCardapioNaoCabe) {
+		t.Fatalf("esperado ErrCardapioNaoCabe, obtido %v", err)
+	}
+	_, err = cardapioExemplo.RenderizarImagem(&OpcoesImagemCardapio{Campus: "xx"})
+	if !errors.Is(err, ErrCampusInvalido) {
+		t.Fatalf("esperado ErrCampusInvalido, obtido %v", err)
+	}
+}
@@ -0,0 +1,146 @@
+package gufu
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+var (
+	ErrEnvelopeSemMarcador = errors.New("envelope inválido: o marcador não foi encontrado")                                 //A resposta ou o requestParams não tem o marcador do Codec
+	ErrEnvelopeSemSenha    = errors.New("envelope inválido: a senha não foi encontrada depois do marcador")                 //O marcador está no fim do texto, sem a senha
+	ErrCifradoInvalido     = errors.New("envelope inválido: o texto criptografado não é um base64 de blocos AES")           //O texto antes do marcador não é base64 ou não tem um tamanho múltiplo de 16 bytes
+	ErrPaddingInvalido     = errors.New("envelope inválido: padding PKCS#7 inválido (a senha ou as chaves estão erradas?)") //O texto foi descriptografado, mas o padding não confere
+	ErrEnvelopeVazio       = errors.New("envelope inválido: o texto descriptografado está vazio")                           //O envelope é válido, mas não contém nada
+)
+
+// Codec contém os parâmetros do envelope de criptografia usado pela API do aplicativo móvel da UFU.
+// O texto é criptografado com AES no modo CBC, usando uma chave derivada com PBKDF2 (SHA-1) de uma senha aleatória,
+// e a senha é enviada junto, depois de um marcador. Se o aplicativo trocar as chaves, basta alterar os campos
+// (ou criar um novo Codec) em vez de esperar uma nova versão da biblioteca.
+type Codec struct {
+	Salt               string //Salt do PBKDF2, em hexadecimal
+	IV                 string //Vetor de inicialização do AES, em hexadecimal
+	TamanhoChave       int    //Tamanho da chave AES em bytes (16 = AES-128)
+	Iteracoes          int    //Número de iterações do PBKDF2
+	MarcadorResposta   string //Separa o texto criptografado da senha nas respostas da API
+	MarcadorRequisicao string //Separa o texto criptografado da senha no campo requestParams das requisições
+	TamanhoSenha       int    //Tamanho da senha aleatória gerada por Encode e EncodeResponse
+
+	//Fonte dos bytes aleatórios das senhas. Se nil (padrão), usa crypto/rand.Reader, que pode ser usado por várias
+	//goroutines ao mesmo tempo. Uma fonte determinística permite testar a saída exata do Codec; nesse caso, ela
+	//precisa ser segura para uso concorrente se o Codec for usado por várias goroutines.
+	Aleatorio io.Reader
+}
+
+// CodecPadrao é o Codec com os parâmetros usados atualmente pelo aplicativo. É usado por Criptografar, Descriptografar
+// e por todas as requisições ao aplicativo. Pode ser alterado para atender necessidades específicas.
+var CodecPadrao = &Codec{
+	Salt:               "3FF2EC019C627B945225DEBAD71A01B6985FE84C95A70EB132882F88C0A59A55",
+	IV:                 "F27D5C9927726BCEFE7510B1BDD3D137",
+	TamanhoChave:       16,
+	Iteracoes:          10,
+	MarcadorResposta:   "G2b1UFYMYjNViPZY6bSpvHnNYxH",
+	MarcadorRequisicao: "Yckn9SAFpqM8K9B2uJYeeHZjFHh",
+	TamanhoSenha:       25,
+}
+
+// Encode prepara um JSON para ser enviado para a API do aplicativo, no formato {"requestParams":"..."},
+// com o texto criptografado, o MarcadorRequisicao e a senha aleatória usada.
+func (c *Codec) Encode(texto string) (string, error) {
+	if texto == "" {
+		return "", errors.New("requestParams está vazio")
+	}
+	senha, err := randomString(c.Aleatorio, c.TamanhoSenha)
+	if err != nil {
+		return "", err
+	}
+	cifrado, err := c.criptografarTexto(texto, senha)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf(`{"requestParams":"%v"}`, cifrado+c.MarcadorRequisicao+senha), nil
+}
+
+// Decode descriptografa uma resposta da API do aplicativo (texto criptografado, MarcadorResposta e senha).
+// Entradas malformadas retornam ErrEnvelopeSemMarcador, ErrEnvelopeSemSenha, ErrCifradoInvalido, ErrPaddingInvalido
+// ou ErrEnvelopeVazio, nunca um pânico.
+func (c *Codec) Decode(corpo string) (string, error) {
+	return c.abrirEnvelope(corpo, c.MarcadorResposta)
+}
+
+// DecodeRequest faz o inverso de Encode: descriptografa o corpo de uma requisição ao aplicativo,
+// no formato {"requestParams":"..."}, e retorna o JSON original. Útil para inspecionar o tráfego do aplicativo.
+func (c *Codec) DecodeRequest(corpo string) (string, error) {
+	var requisicao struct {
+		RequestParams *string `json:"requestParams"`
+	}
+	if err := json.Unmarshal([]byte(corpo), &requisicao); err != nil {
+		return "", fmt.Errorf("o corpo da requisição não é um JSON válido: %w", err)
+	}
+	if requisicao.RequestParams == nil {
+		return "", errors.New("o corpo da requisição não tem o campo requestParams")
+	}
+	return c.abrirEnvelope(*requisicao.RequestParams, c.MarcadorRequisicao)
+}
+
+// EncodeResponse faz o inverso de Decode: criptografa um JSON no formato das respostas da API do aplicativo
+// (texto criptografado, MarcadorResposta e uma senha aleatória). Útil para emular a API localmente.
+func (c *Codec) EncodeResponse(texto string) (string, error) {
+	if texto == "" {
+		return "", errors.New("a resposta está vazia")
+	}
+	senha, err := randomString(c.Aleatorio, c.TamanhoSenha)
+	if err != nil {
+		return "", err
+	}
+	cifrado, err := c.criptografarTexto(texto, senha)
+	if err != nil {
+		return "", err
+	}
+	return cifrado + c.MarcadorResposta + senha, nil
+}
+
+// Separa o texto criptografado e a senha pelo marcador e descriptografa o texto
+func (c *Codec) abrirEnvelope(envelope, marcador string) (string, error) {
+	if marcador == "" {
+		return "", errors.New("o marcador do Codec está vazio")
+	}
+	cifrado, senha, err := separarEnvelope(envelope, marcador)
+	if err != nil {
+		return "", err
+	}
+	texto, err := c.descriptografarTexto(cifrado, senha)
+	if err != nil {
+		return "", err
+	}
+	if texto == "" {
+		return "", ErrEnvelopeVazio
+	}
+	return texto, nil
+}
+
+// Separa o texto criptografado e a senha, que ficam antes e depois da última ocorrência do marcador
+func separarEnvelope(envelope, marcador string) (cifrado, senha string, err error) {
+	inicio := strings.LastIndex(envelope, marcador)
+	if inicio == -1 {
+		return "", "", ErrEnvelopeSemMarcador
+	}
+	senha = envelope[inicio+len(marcador):]
+	if senha == "" {
+		return "", "", ErrEnvelopeSemSenha
+	}
+	return envelope[:inicio], senha, nil
+}
+
+// Criptografa o texto com a senha, retornando o resultado em base64
+func (c *Codec) criptografarTexto(texto, senha string) (string, error) {
+	return encryptAES(c.Salt, c.IV, senha, texto, c.TamanhoChave, c.Iteracoes)
+}
+
+// Descriptografa o texto em base64 com a senha
+func (c *Codec) descriptografarTexto(cifrado, senha string) (string, error) {
+	return decryptAES(c.Salt, c.IV, senha, cifrado, c.TamanhoChave, c.Iteracoes)
+}
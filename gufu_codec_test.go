@@ -0,0 +1,260 @@
+package gufu
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestCodecPadrao(t *testing.T) {
+	//Marcadores usados pelo aplicativo, guardados em base64 nas versões anteriores
+	for marcador, b64 := range map[string]string{
+		CodecPadrao.MarcadorResposta:   "RzJiMVVGWU1Zak5WaVBaWTZiU3B2SG5OWXhI",
+		CodecPadrao.MarcadorRequisicao: "WWNrbjlTQUZwcU04SzlCMnVKWWVlSFpqRkho",
+	} {
+		if base64.StdEncoding.EncodeToString([]byte(marcador)) != b64 {
+			t.Fatalf("marcador alterado: %q", marcador)
+		}
+	}
+
+	texto := `{"login":"fulano","senha":"123"}`
+	corpo := respostaCriptografada(t, texto)
+	decodificado, err := Descriptografar(corpo)
+	if err != nil || decodificado != texto {
+		t.Fatalf("Descriptografar = %q, %v", decodificado, err)
+	}
+	if _, err := Descriptografar("sem marcador"); !errors.Is(err, ErrEnvelopeSemMarcador) {
+		t.Fatalf("esperava ErrEnvelopeSemMarcador, recebeu %v", err)
+	}
+}
+
+func TestCodecEncode(t *testing.T) {
+	codec := *CodecPadrao
+	codec.Salt = "00112233445566778899AABBCCDDEEFF"
+	codec.MarcadorRequisicao = "MARCADOR"
+	codec.TamanhoSenha = 8
+
+	texto := `{"id":1}`
+	codificado, err := codec.Encode(texto)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var corpo struct {
+		RequestParams string `json:"requestParams"`
+	}
+	if err := json.Unmarshal([]byte(codificado), &corpo); err != nil {
+		t.Fatal(err)
+	}
+	cifrado, senha, ok := strings.Cut(corpo.RequestParams, codec.MarcadorRequisicao)
+	if !ok || len(senha) != 8 {
+		t.Fatalf("requestParams inesperado: %q", corpo.RequestParams)
+	}
+	decodificado, err := codec.descriptografarTexto(cifrado, senha)
+	if err != nil || decodificado != texto {
+		t.Fatalf("descriptografarTexto = %q, %v", decodificado, err)
+	}
+	if outro, _ := CodecPadrao.criptografarTexto(texto, senha); outro == cifrado {
+		t.Fatal("um codec com outro salt deveria gerar outro texto criptografado")
+	}
+	if _, err := codec.Encode(""); err == nil {
+		t.Fatal("um JSON vazio deveria retornar erro")
+	}
+}
+
+func TestCodecSimetrico(t *testing.T) {
+	texto := `{"body":"{\"id\":1}","statusCode":200}`
+	requisicao, err := Criptografar(texto)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decodificado, err := DescriptografarRequisicao(requisicao); err != nil || decodificado != texto {
+		t.Fatalf("DescriptografarRequisicao = %q, %v", decodificado, err)
+	}
+	resposta, err := CriptografarResposta(texto)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decodificado, err := Descriptografar(resposta); err != nil || decodificado != texto {
+		t.Fatalf("Descriptografar = %q, %v", decodificado, err)
+	}
+
+	for _, corpo := range []string{
+		"não é json",
+		`{"outro":"campo"}`,
+		`{"requestParams":"sem marcador"}`,
+		`{"requestParams":"abcYckn9SAFpqM8K9B2uJYeeHZjFHh"}`,
+	} {
+		if _, err := DescriptografarRequisicao(corpo); err == nil {
+			t.Errorf("DescriptografarRequisicao(%q) deveria retornar erro", corpo)
+		}
+	}
+	if _, err := CriptografarResposta(""); err == nil {
+		t.Fatal("uma resposta vazia deveria retornar erro")
+	}
+}
+
+func TestCodecEntradasInvalidas(t *testing.T) {
+	marcador := CodecPadrao.MarcadorResposta
+	valido := respostaCriptografada(t, `{"id":1}`)
+	cifrado, _, _ := strings.Cut(valido, marcador)
+	casos := []struct {
+		corpo string
+		erro  error
+	}{
+		{"", ErrEnvelopeSemMarcador},
+		{"abc", ErrEnvelopeSemMarcador},
+		{cifrado + marcador, ErrEnvelopeSemSenha},
+		{marcador + "senha", ErrCifradoInvalido},
+		{"!!!" + marcador + "senha", ErrCifradoInvalido},
+		{"AAAA" + marcador + "senha", ErrCifradoInvalido},
+		{cifrado[:len(cifrado)-4] + marcador + "senhaDeTeste", ErrCifradoInvalido},
+		{cifrado + marcador + "outraSenha", ErrPaddingInvalido},
+	}
+	for _, c := range casos {
+		if _, err := Descriptografar(c.corpo); !errors.Is(err, c.erro) {
+			t.Errorf("Descriptografar(%q) = %v, esperava %v", c.corpo, err, c.erro)
+		}
+	}
+
+	codec := *CodecPadrao
+	codec.IV = "00"
+	if _, err := codec.Decode(valido); err == nil {
+		t.Error("um IV com tamanho errado deveria retornar erro")
+	}
+	if _, err := codec.Encode("{}"); err == nil {
+		t.Error("um IV com tamanho errado deveria retornar erro")
+	}
+	codec = *CodecPadrao
+	codec.TamanhoChave = -1
+	if _, err := codec.Decode(valido); err == nil {
+		t.Error("um tamanho de chave inválido deveria retornar erro")
+	}
+}
+
+func TestPkcs7Unpad(t *testing.T) {
+	bloco := func(ultimos ...byte) []byte {
+		return append(make([]byte, 16-len(ultimos)), ultimos...)
+	}
+	for _, dados := range [][]byte{nil, {1}, bloco(0), bloco(17), bloco(1, 2), bloco(3, 3)} {
+		if _, err := pkcs7Unpad(dados, 16); !errors.Is(err, ErrPaddingInvalido) {
+			t.Errorf("pkcs7Unpad(%v) = %v, esperava ErrPaddingInvalido", dados, err)
+		}
+	}
+	if dados, err := pkcs7Unpad(bloco(2, 2), 16); err != nil || len(dados) != 14 {
+		t.Fatalf("pkcs7Unpad = %v, %v", dados, err)
+	}
+}
+
+func FuzzDescriptografar(f *testing.F) {
+	marcador := CodecPadrao.MarcadorResposta
+	f.Add("")
+	f.Add(marcador)
+	f.Add("qb/ItbJgpIk88xrKTjGpKQ==" + marcador + "HjVSaWQaJcBxegnjiODm3vs2a")
+	f.Add("AAAAAAAAAAAAAAAAAAAAAA==" + marcador + "senha")
+	f.Add("!!!" + marcador + marcador + "senha")
+	f.Fuzz(func(t *testing.T, corpo string) {
+		texto, err := Descriptografar(corpo)
+		if err == nil && texto == "" {
+			t.Fatal("um texto vazio deveria retornar ErrEnvelopeVazio")
+		}
+	})
+}
+
+func FuzzDescriptografarRequisicao(f *testing.F) {
+	marcador := CodecPadrao.MarcadorRequisicao
+	f.Add(`{"requestParams":"qb/ItbJgpIk88xrKTjGpKQ==` + marcador + `senha"}`)
+	f.Add(`{"requestParams":null}`)
+	f.Add(`{"requestParams":"` + marcador + `"}`)
+	f.Add(`[]`)
+	f.Fuzz(func(t *testing.T, corpo string) {
+		DescriptografarRequisicao(corpo)
+	})
+}
+
+func FuzzCodecIdaEVolta(f *testing.F) {
+	f.Add(`{"id":1}`)
+	f.Add("á")
+	f.Add(strings.Repeat("x", 16))
+	f.Fuzz(func(t *testing.T, texto string) {
+		if texto == "" {
+			return
+		}
+		resposta, err := CriptografarResposta(texto)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if decodificado, err := Descriptografar(resposta); err != nil || decodificado != texto {
+			t.Fatalf("Descriptografar = %q, %v", decodificado, err)
+		}
+	})
+}
+
+// Fonte determinística de bytes (0, 1, 2, ..., 255, 0, 1, ...) para testar a saída exata do Codec
+type bytesSequenciais struct{ proximo byte }
+
+func (b *bytesSequenciais) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = b.proximo
+		b.proximo++
+	}
+	return len(p), nil
+}
+
+func TestCodecDeterministico(t *testing.T) {
+	codec := *CodecPadrao
+	codec.Aleatorio = &bytesSequenciais{}
+	requisicao, err := codec.Encode(`{"login":"fulano"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resposta, err := codec.EncodeResponse(`{"id":1}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if esperado := `{"requestParams":"hYrSAXqww5W1w697HlbPA+5DHOquF9t06d3MM+MDvqg=Yckn9SAFpqM8K9B2uJYeeHZjFHhabcdefghijklmnopqrstuvwxy"}`; requisicao != esperado {
+		t.Errorf("Encode = %s, esperava %s", requisicao, esperado)
+	}
+	if esperado := "eGBdFWLWTG4ty2+zHzujUQ==G2b1UFYMYjNViPZY6bSpvHnNYxHGHIJKLMNOPQRSTUVWXYZabcde"; resposta != esperado {
+		t.Errorf("EncodeResponse = %s, esperava %s", resposta, esperado)
+	}
+
+	//Os bytes a partir de 208 são descartados para que todas as letras tenham a mesma chance
+	senha, err := randomString(bytes.NewReader(append(bytes.Repeat([]byte{208, 255}, 6), 0, 51, 52, 0)), 3)
+	if err != nil || senha != "aZa" {
+		t.Fatalf("randomString = %q, %v", senha, err)
+	}
+	if _, err := randomString(bytes.NewReader(nil), 3); err == nil {
+		t.Fatal("uma fonte sem bytes deveria retornar erro")
+	}
+}
+
+func TestSenhasConcorrentes(t *testing.T) {
+	var (
+		mu     sync.Mutex
+		senhas = map[string]bool{}
+		wg     sync.WaitGroup
+	)
+	for range 200 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			requisicao, err := Criptografar(`{"id":1}`)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			_, senha, _ := strings.Cut(requisicao, CodecPadrao.MarcadorRequisicao)
+			mu.Lock()
+			senhas[senha] = true
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	if len(senhas) != 200 {
+		t.Fatalf("as senhas deveriam ser diferentes: %d senhas únicas", len(senhas))
+	}
+}
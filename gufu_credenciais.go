@@ -0,0 +1,153 @@
+package gufu
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+var (
+	ErrCredencialAppInvalida = errors.New("credencial do aplicativo inválida: o usuário está vazio") //Erro retornado por NovasCredenciaisApp, CredenciaisApp.Definir e CredenciaisApp.Carregar.
+	ErrSemCredencialApp      = errors.New("não há uma credencial do aplicativo para o endpoint")     //Erro retornado por CallMobile, sem fazer a requisição, quando a autorização é vazia e o endpoint não tem credencial.
+)
+
+// Usuário e senha do aplicativo enviados no cabeçalho Authorization (Basic) das chamadas à API do aplicativo.
+// Não são as credenciais do aluno: cada grupo de endpoints da API usa um usuário próprio do aplicativo.
+type CredencialApp struct {
+	Usuario string `json:"usuario"` //Ex: user-autenticado
+	Senha   string `json:"senha"`
+}
+
+// Retorna o valor do cabeçalho Authorization da credencial (Ex: "Basic dXNlci1...")
+func (c CredencialApp) Autorizacao() string {
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(c.Usuario+":"+c.Senha))
+}
+
+// Registro das credenciais do aplicativo por endpoint, usado por CallMobile quando a autorização não é informada.
+// Pode ser usado por várias goroutines ao mesmo tempo, então as credenciais podem ser trocadas com o programa rodando.
+// O valor zero é um registro vazio, pronto para uso.
+type CredenciaisApp struct {
+	mu          sync.RWMutex
+	porEndpoint map[string]CredencialApp
+}
+
+// Cria um registro com as credenciais informadas, indexadas pelo endpoint (Ex: /autenticacao/autenticarV2).
+// Retorna ErrCredencialAppInvalida se alguma credencial não tiver usuário.
+func NovasCredenciaisApp(credenciais map[string]CredencialApp) (*CredenciaisApp, error) {
+	if err := validarCredenciais(credenciais); err != nil {
+		return nil, err
+	}
+	c := &CredenciaisApp{porEndpoint: make(map[string]CredencialApp, len(credenciais))}
+	for endpoint, credencial := range credenciais {
+		c.porEndpoint[endpoint] = credencial
+	}
+	return c, nil
+}
+
+func validarCredenciais(credenciais map[string]CredencialApp) error {
+	for endpoint, credencial := range credenciais {
+		if credencial.Usuario == "" {
+			return fmt.Errorf("%w (endpoint %s)", ErrCredencialAppInvalida, endpoint)
+		}
+	}
+	return nil
+}
+
+// Credenciais usadas hoje pelo aplicativo oficial
+func credenciaisPadrao() map[string]CredencialApp {
+	return map[string]CredencialApp{
+		"/autenticacao/autenticarV2":        {Usuario: "user-autenticado", Senha: "E4YBcPbdMAVrVUwfDIo5A"},
+		"/identidade-digital/buscarByToken": {Usuario: "user-estudante", Senha: "EyHqhP5NDCyN4kCHcYUIa"},
+	}
+}
+
+// Credenciais do aplicativo usadas por CallMobile (e, portanto, por LoginViaMobile e BuscarIdentidadeDigital).
+// Começa com as credenciais do aplicativo oficial, que podem ser trocadas com Definir ou Carregar se o aplicativo mudar.
+// Para usar outras credenciais em apenas algumas chamadas, veja ComCredenciaisApp.
+var Credenciais = &CredenciaisApp{porEndpoint: credenciaisPadrao()}
+
+// Retorna a credencial do endpoint, se houver uma.
+func (c *CredenciaisApp) Obter(endpoint string) (CredencialApp, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	credencial, ok := c.porEndpoint[endpoint]
+	return credencial, ok
+}
+
+// Adiciona ou troca a credencial do endpoint.
+func (c *CredenciaisApp) Definir(endpoint string, credencial CredencialApp) error {
+	if credencial.Usuario == "" {
+		return ErrCredencialAppInvalida
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.porEndpoint == nil {
+		c.porEndpoint = map[string]CredencialApp{}
+	}
+	c.porEndpoint[endpoint] = credencial
+	return nil
+}
+
+// Remove a credencial do endpoint. As chamadas ao endpoint sem autorização passam a usar a credencial de Credenciais
+// (se este não for o próprio Credenciais) ou, se não houver, a falhar com ErrSemCredencialApp.
+func (c *CredenciaisApp) Remover(endpoint string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.porEndpoint, endpoint)
+}
+
+// Carrega credenciais de um JSON no formato {"/endpoint": {"usuario": "...", "senha": "..."}}, adicionando ou trocando
+// as credenciais dos endpoints listados. Permite trocar as credenciais por um arquivo de configuração, sem recompilar.
+// Se alguma credencial for inválida, nenhuma é alterada.
+func (c *CredenciaisApp) Carregar(r io.Reader) error {
+	var credenciais map[string]CredencialApp
+	if err := json.NewDecoder(r).Decode(&credenciais); err != nil {
+		return fmt.Errorf("erro ao ler as credenciais do aplicativo: %w", err)
+	}
+	if err := validarCredenciais(credenciais); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.porEndpoint == nil {
+		c.porEndpoint = make(map[string]CredencialApp, len(credenciais))
+	}
+	for endpoint, credencial := range credenciais {
+		c.porEndpoint[endpoint] = credencial
+	}
+	return nil
+}
+
+// Volta para as credenciais do aplicativo oficial, descartando as que foram definidas ou carregadas.
+func (c *CredenciaisApp) Reiniciar() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.porEndpoint = credenciaisPadrao()
+}
+
+type chaveCredenciaisApp struct{}
+
+// Retorna um contexto que faz CallMobile usar as credenciais informadas no lugar de Credenciais.
+// Os endpoints que não estiverem em credenciais continuam usando Credenciais, então basta informar as que mudam.
+// Útil para programas que atendem vários clientes, cada um com suas próprias credenciais do aplicativo.
+func ComCredenciaisApp(ctx context.Context, credenciais *CredenciaisApp) context.Context {
+	return context.WithValue(ctx, chaveCredenciaisApp{}, credenciais)
+}
+
+// Retorna o cabeçalho Authorization do endpoint, usando as credenciais do contexto ou, se o endpoint não estiver
+// nelas, Credenciais. Retorna ErrSemCredencialApp se o endpoint não tiver uma credencial.
+func autorizacaoDoEndpoint(ctx context.Context, endpoint string) (string, error) {
+	if credenciais, ok := ctx.Value(chaveCredenciaisApp{}).(*CredenciaisApp); ok && credenciais != nil {
+		if credencial, ok := credenciais.Obter(endpoint); ok {
+			return credencial.Autorizacao(), nil
+		}
+	}
+	if credencial, ok := Credenciais.Obter(endpoint); ok {
+		return credencial.Autorizacao(), nil
+	}
+	return "", fmt.Errorf("%w %s", ErrSemCredencialApp, endpoint)
+}
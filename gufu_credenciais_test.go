@@ -0,0 +1,145 @@
+package gufu
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestCredenciaisApp(t *testing.T) {
+	t.Cleanup(Credenciais.Reiniciar)
+	if c := (CredencialApp{Usuario: "user-estudante", Senha: "EyHqhP5NDCyN4kCHcYUIa"}); c.Autorizacao() != "Basic dXNlci1lc3R1ZGFudGU6RXlIcWhQNU5EQ3lONGtDSGNZVUlh" {
+		t.Fatalf("Autorizacao = %q", c.Autorizacao())
+	}
+
+	var recebidas []string
+	servidorMobile(t, func(r *http.Request, corpo string) (int, string) {
+		recebidas = append(recebidas, r.Header.Get("Authorization"))
+		return http.StatusOK, `{"ok":true}`
+	})
+	chamar := func(ctx context.Context, endpoint, autorizacao string) {
+		t.Helper()
+		if _, err := CallMobile[struct{}, map[string]bool](ctx, endpoint, struct{}{}, autorizacao); err != nil {
+			t.Fatal(err)
+		}
+	}
+	semCredencial := func(ctx context.Context, endpoint string) {
+		t.Helper()
+		if _, err := CallMobile[struct{}, map[string]bool](ctx, endpoint, struct{}{}, ""); !errors.Is(err, ErrSemCredencialApp) {
+			t.Fatalf("esperava ErrSemCredencialApp, recebeu %v", err)
+		}
+	}
+
+	if err := Credenciais.Definir("/novo", CredencialApp{}); !errors.Is(err, ErrCredencialAppInvalida) {
+		t.Fatalf("esperava ErrCredencialAppInvalida, recebeu %v", err)
+	}
+	if err := Credenciais.Definir("/novo", CredencialApp{Usuario: "user-novo", Senha: "s"}); err != nil {
+		t.Fatal(err)
+	}
+	chamar(context.Background(), "/novo", "")
+	chamar(context.Background(), "/novo", "Basic explicito")
+	semCredencial(context.Background(), "/sem-credencial")
+
+	err := Credenciais.Carregar(strings.NewReader(`{"/novo":{"usuario":"user-rotacionado","senha":"t"},"/outro":{"senha":"x"}}`))
+	if !errors.Is(err, ErrCredencialAppInvalida) {
+		t.Fatalf("esperava ErrCredencialAppInvalida, recebeu %v", err)
+	}
+	if err := Credenciais.Carregar(strings.NewReader(`{"/novo":{"usuario":"user-rotacionado","senha":"t"}}`)); err != nil {
+		t.Fatal(err)
+	}
+	chamar(context.Background(), "/novo", "")
+	cliente, err := NovasCredenciaisApp(map[string]CredencialApp{"/novo": {Usuario: "user-cliente", Senha: "c"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	chamar(ComCredenciaisApp(context.Background(), cliente), "/novo", "")
+	//Os endpoints que o cliente não informa usam Credenciais
+	chamar(ComCredenciaisApp(context.Background(), cliente), "/identidade-digital/buscarByToken", "")
+	Credenciais.Remover("/novo")
+	semCredencial(context.Background(), "/novo")
+	chamar(ComCredenciaisApp(context.Background(), cliente), "/novo", "")
+	semCredencial(ComCredenciaisApp(context.Background(), cliente), "/sem-credencial")
+
+	esperadas := []string{
+		CredencialApp{Usuario: "user-novo", Senha: "s"}.Autorizacao(),
+		"Basic explicito",
+		CredencialApp{Usuario: "user-rotacionado", Senha: "t"}.Autorizacao(),
+		CredencialApp{Usuario: "user-cliente", Senha: "c"}.Autorizacao(),
+		CredencialApp{Usuario: "user-estudante", Senha: "EyHqhP5NDCyN4kCHcYUIa"}.Autorizacao(),
+		CredencialApp{Usuario: "user-cliente", Senha: "c"}.Autorizacao(),
+	}
+	if strings.Join(recebidas, "|") != strings.Join(esperadas, "|") {
+		t.Fatalf("cabeçalhos recebidos %q, esperava %q", recebidas, esperadas)
+	}
+
+	Credenciais.Reiniciar()
+	if _, ok := Credenciais.Obter("/autenticacao/autenticarV2"); !ok {
+		t.Fatal("Reiniciar não restaurou as credenciais padrão")
+	}
+}
+
+func TestLoginViaMobileComCredenciaisDoCliente(t *testing.T) {
+	cliente, err := NovasCredenciaisApp(map[string]CredencialApp{
+		"/autenticacao/autenticarV2":        {Usuario: "login-cliente", Senha: "a"},
+		"/identidade-digital/buscarByToken": {Usuario: "identidade-cliente", Senha: "b"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	servidorMobile(t, func(r *http.Request, corpo string) (int, string) {
+		switch r.URL.Path {
+		case "/mobile/autenticacao/autenticarV2":
+			if r.Header.Get("Authorization") != (CredencialApp{Usuario: "login-cliente", Senha: "a"}).Autorizacao() {
+				return http.StatusUnauthorized, `{"status":401,"error":"Unauthorized","message":"Bad credentials"}`
+			}
+			return http.StatusOK, `{"resultType":"SUCCESS","token":"tk","perfilAtivo":{"idPerfil":7}}`
+		case "/mobile/identidade-digital/buscarByToken":
+			if r.Header.Get("Authorization") != (CredencialApp{Usuario: "identidade-cliente", Senha: "b"}).Autorizacao() {
+				return http.StatusUnauthorized, `{"status":401,"error":"Unauthorized","message":"Bad credentials"}`
+			}
+			return http.StatusOK, `{"nome":"Fulano de Tal"}`
+		}
+		return http.StatusNotFound, ""
+	})
+
+	//Sem o contexto, as credenciais padrão são recusadas pelo servidor de teste
+	var erroStatus *ErroStatusMobile
+	if _, err := LoginViaMobile("fulano", "certa"); !errors.As(err, &erroStatus) || erroStatus.Status != http.StatusUnauthorized {
+		t.Fatalf("esperava status 401, recebeu %v", err)
+	}
+	ctx := ComCredenciaisApp(context.Background(), cliente)
+	login, err := LoginViaMobileComContexto(ctx, "fulano", "certa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	identidade, err := login.BuscarIdentidadeDigitalComContexto(ctx)
+	if err != nil || identidade.Nome != "Fulano de Tal" {
+		t.Fatalf("BuscarIdentidadeDigitalComContexto = %+v, %v", identidade, err)
+	}
+}
+
+func TestCredenciaisAppValorZero(t *testing.T) {
+	if _, err := NovasCredenciaisApp(map[string]CredencialApp{"/x": {Senha: "s"}}); !errors.Is(err, ErrCredencialAppInvalida) {
+		t.Fatalf("esperava ErrCredencialAppInvalida, recebeu %v", err)
+	}
+
+	var vazio CredenciaisApp
+	if _, ok := vazio.Obter("/x"); ok {
+		t.Fatal("o registro vazio não deveria ter credenciais")
+	}
+	if err := vazio.Definir("/x", CredencialApp{Usuario: "u"}); err != nil {
+		t.Fatal(err)
+	}
+	var outro CredenciaisApp
+	if err := outro.Carregar(strings.NewReader(`{"/y":{"usuario":"v"}}`)); err != nil {
+		t.Fatal(err)
+	}
+	if c, ok := vazio.Obter("/x"); !ok || c.Usuario != "u" {
+		t.Fatalf("Obter = %+v, %v", c, ok)
+	}
+	if c, ok := outro.Obter("/y"); !ok || c.Usuario != "v" {
+		t.Fatalf("Obter = %+v, %v", c, ok)
+	}
+}
@@ -6,88 +6,90 @@ import (
 	"crypto/sha1"
 	"encoding/base64"
 	"encoding/hex"
-	"errors"
-	"strings"
+	"fmt"
 
 	"golang.org/x/crypto/pbkdf2"
 )
 
-// Gera uma chave usando PBKDF2 com o salt e a palavra-chave
+// Gera uma chave usando PBKDF2 (Password-Based Key Derivation Function 2) com o salt e a palavra-chave.
+// Usada tanto para criptografar quanto para descriptografar.
 func generateKey(salt, passphrase string, keySize, iterationCount int) ([]byte, error) {
 	saltBytes, err := hex.DecodeString(salt)
 	if err != nil {
 		return nil, err
 	}
+	if keySize != 16 && keySize != 24 && keySize != 32 {
+		return nil, fmt.Errorf("tamanho de chave AES inválido: %d", keySize)
+	}
+	if iterationCount < 1 {
+		return nil, fmt.Errorf("número de iterações do PBKDF2 inválido: %d", iterationCount)
+	}
 	key := pbkdf2.Key([]byte(passphrase), saltBytes, iterationCount, keySize, sha1.New)
 	return key, nil
 }
 
-// Descriptografa o texto cifrado fornecido usando AES no modo CBC
+// Decodifica o IV em hexadecimal, que precisa ter o tamanho de um bloco AES
+func decodeIV(iv string) ([]byte, error) {
+	ivBytes, err := hex.DecodeString(iv)
+	if err != nil {
+		return nil, err
+	}
+	if len(ivBytes) != aes.BlockSize {
+		return nil, fmt.Errorf("o IV precisa ter %d bytes, tem %d", aes.BlockSize, len(ivBytes))
+	}
+	return ivBytes, nil
+}
+
+// Descriptografa o texto cifrado fornecido (em base64) usando AES no modo CBC
 func decryptAES(salt, iv, passphrase, ciphertext string, keySize, iterationCount int) (string, error) {
-	key, err := generateKey(salt, passphrase, keySize, iterationCount)
+	ciphertextBytes, err := base64.StdEncoding.DecodeString(ciphertext)
 	if err != nil {
-		return "", err
+		return "", fmt.Errorf("%w: %v", ErrCifradoInvalido, err)
 	}
-	ivBytes, err := hex.DecodeString(iv)
+	plainBytes, err := decryptAESBytes(salt, iv, passphrase, ciphertextBytes, keySize, iterationCount)
 	if err != nil {
 		return "", err
 	}
-	ciphertextBytes, err := base64.StdEncoding.DecodeString(ciphertext)
+	return string(plainBytes), nil
+}
+
+// Descriptografa os bytes usando AES no modo CBC no próprio slice, sem alocar outro, e remove o padding
+func decryptAESBytes(salt, iv, passphrase string, ciphertext []byte, keySize, iterationCount int) ([]byte, error) {
+	if len(ciphertext) == 0 || len(ciphertext)%aes.BlockSize != 0 {
+		return nil, fmt.Errorf("%w: %d bytes não é um múltiplo de %d", ErrCifradoInvalido, len(ciphertext), aes.BlockSize)
+	}
+	key, err := generateKey(salt, passphrase, keySize, iterationCount)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	ivBytes, err := decodeIV(iv)
+	if err != nil {
+		return nil, err
 	}
 	block, err := aes.NewCipher(key)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	mode := cipher.NewCBCDecrypter(block, ivBytes)
-	plainBytes := make([]byte, len(ciphertextBytes))
-	mode.CryptBlocks(plainBytes, ciphertextBytes)
-	plainBytes = pkcs7Unpad(plainBytes, aes.BlockSize)
-	return string(plainBytes), nil
+	mode.CryptBlocks(ciphertext, ciphertext)
+	return pkcs7Unpad(ciphertext, aes.BlockSize)
 }
 
-// Remove o padding do PKCS7
-func pkcs7Unpad(data []byte, blockSize int) []byte {
+// Remove o padding do PKCS7, conferindo se todos os bytes do padding estão corretos.
+// Um padding inválido geralmente indica que a senha ou as chaves estão erradas.
+func pkcs7Unpad(data []byte, blockSize int) ([]byte, error) {
 	length := len(data)
-	unpadding := int(data[length-1])
-	return data[:(length - unpadding)]
-}
-
-// Descriptografa o texto usando a senha
-func decryptText(encryptedText, passphrase string) (string, error) {
-	const (
-		salt      = "3FF2EC019C627B945225DEBAD71A01B6985FE84C95A70EB132882F88C0A59A55"
-		iv        = "F27D5C9927726BCEFE7510B1BDD3D137"
-		keySize   = 16
-		iterCount = 10
-	)
-	return decryptAES(salt, iv, passphrase, encryptedText, keySize, iterCount)
-}
-
-func decryptEncodedJson(encodedJSONStr string) (string, error) {
-	const markerB64 = "RzJiMVVGWU1Zak5WaVBaWTZiU3B2SG5OWXhI"
-	marker, err := base64.StdEncoding.DecodeString(markerB64)
-	if err != nil {
-		return "", err
+	if length == 0 || length%blockSize != 0 {
+		return nil, ErrPaddingInvalido
 	}
-	markerStr := string(marker)
-	startIndex := strings.LastIndex(encodedJSONStr, markerStr)
-	if startIndex == -1 {
-		return "", nil
+	unpadding := int(data[length-1])
+	if unpadding == 0 || unpadding > blockSize {
+		return nil, ErrPaddingInvalido
 	}
-	passphrase := encodedJSONStr[startIndex+len(markerStr):]
-	if passphrase != "" {
-		encryptedText := encodedJSONStr[:startIndex]
-		decryptedText, err := decryptText(encryptedText, passphrase)
-		if err != nil {
-			return "", err
+	for _, b := range data[length-unpadding:] {
+		if int(b) != unpadding {
+			return nil, ErrPaddingInvalido
 		}
-		if decryptedText == "" {
-			return "", errors.New("decryptor returned an empty string")
-		}
-
-		return decryptedText, nil
 	}
-	return "", nil
+	return data[:(length - unpadding)], nil
 }
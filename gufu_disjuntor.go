@@ -0,0 +1,214 @@
+package gufu
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrCircuitoAberto = errors.New("circuito aberto: o serviço está indisponível, tente novamente mais tarde") //Erro retornado, sem fazer a requisição, enquanto o disjuntor do serviço estiver aberto.
+
+// EstadoDisjuntor é o estado do disjuntor (circuit breaker) de um serviço.
+type EstadoDisjuntor int
+
+const (
+	DisjuntorFechado    EstadoDisjuntor = iota //As requisições são feitas normalmente
+	DisjuntorAberto                            //As requisições falham imediatamente com ErrCircuitoAberto
+	DisjuntorMeioAberto                        //Algumas requisições de teste são liberadas para verificar se o serviço voltou
+)
+
+func (e EstadoDisjuntor) String() string {
+	switch e {
+	case DisjuntorFechado:
+		return "fechado"
+	case DisjuntorAberto:
+		return "aberto"
+	case DisjuntorMeioAberto:
+		return "meio-aberto"
+	}
+	return "desconhecido"
+}
+
+// ConfiguracaoDisjuntor contém os limites usados pelos disjuntores de cada serviço.
+type ConfiguracaoDisjuntor struct {
+	Desativado            bool          //Se true, as requisições nunca são bloqueadas
+	FalhasParaAbrir       int           //Falhas seguidas (erro de rede, timeout ou status 5xx) que abrem o disjuntor
+	TempoAberto           time.Duration //Tempo que o disjuntor fica aberto antes de liberar requisições de teste
+	RequisicoesMeioAberto int           //Requisições de teste simultâneas liberadas no estado meio-aberto
+	SucessosParaFechar    int           //Sucessos seguidos no estado meio-aberto que fecham o disjuntor
+}
+
+// Disjuntor é a configuração dos disjuntores. Cada Servico tem o seu próprio disjuntor, então uma falha no aplicativo
+// não bloqueia o SSO, por exemplo. Pode ser alterado para atender necessidades específicas, mas apenas antes de fazer
+// requisições: ele é lido sem sincronização pelas requisições em andamento, como ClienteHTTP.
+var Disjuntor = ConfiguracaoDisjuntor{
+	FalhasParaAbrir:       5,
+	TempoAberto:           30 * time.Second,
+	RequisicoesMeioAberto: 1,
+	SucessosParaFechar:    1,
+}
+
+type disjuntor struct {
+	mu       sync.Mutex
+	estado   EstadoDisjuntor
+	falhas   int
+	sucessos int
+	testes   int //Requisições de teste em andamento no estado meio-aberto
+	abertoEm time.Time
+}
+
+var (
+	disjuntores   = map[Servico]*disjuntor{}
+	disjuntoresMu sync.Mutex
+)
+
+func disjuntorDe(servico Servico) *disjuntor {
+	disjuntoresMu.Lock()
+	defer disjuntoresMu.Unlock()
+	d, ok := disjuntores[servico]
+	if !ok {
+		d = &disjuntor{}
+		disjuntores[servico] = d
+	}
+	return d
+}
+
+// EstadoDoDisjuntor retorna o estado atual do disjuntor do serviço.
+func EstadoDoDisjuntor(servico Servico) EstadoDisjuntor {
+	d := disjuntorDe(servico)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.estado == DisjuntorAberto && time.Since(d.abertoEm) >= Disjuntor.TempoAberto {
+		return DisjuntorMeioAberto
+	}
+	return d.estado
+}
+
+// ReiniciarDisjuntores fecha os disjuntores de todos os serviços e zera as contagens de falhas.
+func ReiniciarDisjuntores() {
+	disjuntoresMu.Lock()
+	defer disjuntoresMu.Unlock()
+	clear(disjuntores)
+}
+
+// Verifica se uma requisição pode ser feita. Se puder, retorna a função que deve ser chamada com o resultado dela.
+func (d *disjuntor) permitir(servico Servico) (func(falhou bool), error) {
+	if Disjuntor.Desativado {
+		return func(bool) {}, nil
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.estado == DisjuntorAberto {
+		if tentarEm := d.abertoEm.Add(Disjuntor.TempoAberto); time.Now().Before(tentarEm) {
+			return nil, fmt.Errorf("%w (%s, nova tentativa em %v)", ErrCircuitoAberto, servico, time.Until(tentarEm).Round(time.Second))
+		}
+		d.estado, d.sucessos, d.testes = DisjuntorMeioAberto, 0, 0
+	}
+	teste := d.estado == DisjuntorMeioAberto
+	if teste {
+		if d.testes >= max(Disjuntor.RequisicoesMeioAberto, 1) {
+			return nil, fmt.Errorf("%w (%s, aguardando as requisições de teste)", ErrCircuitoAberto, servico)
+		}
+		d.testes++
+	}
+	return func(falhou bool) { d.registrar(teste, falhou) }, nil
+}
+
+func (d *disjuntor) registrar(teste, falhou bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if teste {
+		d.testes--
+	}
+	switch {
+	case falhou && (d.estado == DisjuntorMeioAberto || d.falhas+1 >= max(Disjuntor.FalhasParaAbrir, 1)):
+		d.estado, d.abertoEm, d.falhas = DisjuntorAberto, time.Now(), 0
+	case falhou:
+		d.falhas++
+	case d.estado == DisjuntorMeioAberto:
+		d.sucessos++
+		if d.sucessos >= max(Disjuntor.SucessosParaFechar, 1) {
+			d.estado, d.falhas = DisjuntorFechado, 0
+		}
+	default:
+		d.falhas = 0
+	}
+}
+
+// Informa se o resultado de uma requisição conta como falha do serviço. Cancelamentos feitos por quem chamou não contam.
+func falhaDoServico(res *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled)
+	}
+	return res.StatusCode >= 500
+}
+
+type chaveIgnorarDisjuntor struct{}
+
+// Marca o contexto para que as requisições feitas com ele não sejam bloqueadas nem contadas pelo disjuntor.
+// Usado por Verificar, que precisa saber o estado real dos serviços.
+func ignorarDisjuntor(ctx context.Context) context.Context {
+	return context.WithValue(ctx, chaveIgnorarDisjuntor{}, true)
+}
+
+var (
+	CacheCardapios            = false          //Se true, os cardápios obtidos são guardados em memória e usados quando a API do aplicativo falhar (inclusive com ErrCircuitoAberto).
+	IdadeMaximaCacheCardapios = 24 * time.Hour //Idade máxima dos cardápios guardados para serem usados quando a API falhar.
+
+	cacheCardapios   = map[string]cardapiosEmCache{}
+	cacheCardapiosMu sync.Mutex
+)
+
+type cardapiosEmCache struct {
+	corpo    string
+	obtidoEm time.Time
+}
+
+// Obtém e descriptografa os cardápios de url. Com CacheCardapios ativado, guarda a resposta e, se a requisição falhar
+// (erro de rede, status 5xx ou ErrCircuitoAberto), retorna a última resposta guardada que não for mais velha que
+// IdadeMaximaCacheCardapios. A resposta vazia ("{}") significa que não há refeições: é retornada como veio, sem
+// ser guardada, para que os cardápios de outro dia não sejam retornados no lugar dela.
+func buscarCardapios(url string) (string, error) {
+	corpo, err := buscarCardapiosSemCache(url)
+	if !CacheCardapios {
+		return corpo, err
+	}
+	if err == nil {
+		if strings.TrimSpace(corpo) != "{}" {
+			cacheCardapiosMu.Lock()
+			cacheCardapios[url] = cardapiosEmCache{corpo: corpo, obtidoEm: time.Now()}
+			cacheCardapiosMu.Unlock()
+		}
+		return corpo, nil
+	}
+	cacheCardapiosMu.Lock()
+	defer cacheCardapiosMu.Unlock()
+	if guardado, ok := cacheCardapios[url]; ok && time.Since(guardado.obtidoEm) <= IdadeMaximaCacheCardapios {
+		if Logger != nil {
+			Logger.Warn("gufu: usando cardápios guardados", "erro", err.Error(), "obtidoEm", guardado.obtidoEm)
+		}
+		return guardado.corpo, nil
+	}
+	return corpo, err
+}
+
+func buscarCardapiosSemCache(url string) (string, error) {
+	resp, err := requisiçãoGenerica(context.Background(), ServicoMobile, url, http.MethodGet, nil)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 500 {
+		return "", fmt.Errorf("algo deu errado ao obter os cardápios, status http: %v", resp.StatusCode)
+	}
+	bodyResp, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	return descriptografarResposta(resp.Request, ServicoMobile, bodyResp)
+}
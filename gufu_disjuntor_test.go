@@ -0,0 +1,151 @@
+package gufu
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDisjuntor(t *testing.T) {
+	var consultas atomic.Int32
+	var fora atomic.Bool
+	fora.Store(true)
+	servidor := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		consultas.Add(1)
+		if fora.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, `{"identidadeDigital":{"id":1},"dataNascimentoString":"01/01/2000"}`)
+	}))
+	defer servidor.Close()
+	apontarApisPara(t, servidor)
+	configuracao := Disjuntor
+	Disjuntor = ConfiguracaoDisjuntor{FalhasParaAbrir: 3, TempoAberto: 50 * time.Millisecond, RequisicoesMeioAberto: 1, SucessosParaFechar: 1}
+	defer func() { Disjuntor = configuracao }()
+
+	for range 3 {
+		if _, err := ObterIdUfu("12352998224725"); errors.Is(err, ErrCircuitoAberto) {
+			t.Fatal("o disjuntor não deveria abrir antes de 3 falhas")
+		}
+	}
+	if EstadoDoDisjuntor(ServicoValida) != DisjuntorAberto {
+		t.Fatalf("o disjuntor deveria estar aberto, está %v", EstadoDoDisjuntor(ServicoValida))
+	}
+	if EstadoDoDisjuntor(ServicoMobile) != DisjuntorFechado {
+		t.Fatal("o disjuntor dos outros serviços não deveria abrir")
+	}
+	if _, err := ObterIdUfu("12352998224725"); !errors.Is(err, ErrCircuitoAberto) {
+		t.Fatalf("esperava ErrCircuitoAberto, recebeu %v", err)
+	}
+	if consultas.Load() != 3 {
+		t.Fatalf("com o disjuntor aberto a requisição não deveria ser feita: %d consultas", consultas.Load())
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if EstadoDoDisjuntor(ServicoValida) != DisjuntorMeioAberto {
+		t.Fatalf("o disjuntor deveria estar meio-aberto, está %v", EstadoDoDisjuntor(ServicoValida))
+	}
+	//A requisição de teste falha e o disjuntor abre de novo
+	ObterIdUfu("12352998224725")
+	if EstadoDoDisjuntor(ServicoValida) != DisjuntorAberto {
+		t.Fatalf("uma falha no estado meio-aberto deveria abrir o disjuntor, está %v", EstadoDoDisjuntor(ServicoValida))
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	fora.Store(false)
+	if _, err := ObterIdUfu("12352998224725"); err != nil {
+		t.Fatal(err)
+	}
+	if EstadoDoDisjuntor(ServicoValida) != DisjuntorFechado {
+		t.Fatalf("um sucesso no estado meio-aberto deveria fechar o disjuntor, está %v", EstadoDoDisjuntor(ServicoValida))
+	}
+}
+
+func TestDisjuntorMeioAbertoLimitaTestes(t *testing.T) {
+	ReiniciarDisjuntores()
+	defer ReiniciarDisjuntores()
+	configuracao := Disjuntor
+	Disjuntor = ConfiguracaoDisjuntor{FalhasParaAbrir: 1, TempoAberto: time.Millisecond, RequisicoesMeioAberto: 1, SucessosParaFechar: 2}
+	defer func() { Disjuntor = configuracao }()
+
+	d := disjuntorDe(ServicoSSO)
+	registrar, _ := d.permitir(ServicoSSO)
+	registrar(true)
+	time.Sleep(2 * time.Millisecond)
+
+	registrar, err := d.permitir(ServicoSSO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.permitir(ServicoSSO); !errors.Is(err, ErrCircuitoAberto) {
+		t.Fatalf("apenas uma requisição de teste deveria ser liberada, erro: %v", err)
+	}
+	registrar(false)
+	if EstadoDoDisjuntor(ServicoSSO) != DisjuntorMeioAberto {
+		t.Fatal("deveriam ser necessários 2 sucessos para fechar o disjuntor")
+	}
+	registrar, _ = d.permitir(ServicoSSO)
+	registrar(false)
+	if EstadoDoDisjuntor(ServicoSSO) != DisjuntorFechado {
+		t.Fatalf("o disjuntor deveria estar fechado, está %v", EstadoDoDisjuntor(ServicoSSO))
+	}
+}
+
+func TestCacheCardapios(t *testing.T) {
+	cardapios := respostaCriptografada(t, `[{"nid":"277","titulo":"Cardápio"}]`)
+	vaziaCriptografada := respostaCriptografada(t, `{}`)
+	var fora, vazia atomic.Bool
+	servidor := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fora.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		if vazia.Load() {
+			fmt.Fprint(w, vaziaCriptografada)
+			return
+		}
+		fmt.Fprint(w, cardapios)
+	}))
+	defer servidor.Close()
+	apontarApisPara(t, servidor)
+	cache, idade := CacheCardapios, IdadeMaximaCacheCardapios
+	defer func() {
+		CacheCardapios, IdadeMaximaCacheCardapios = cache, idade
+		clear(cacheCardapios)
+	}()
+
+	fora.Store(true)
+	if _, err := ObterTodosOsCardapios(); err == nil {
+		t.Fatal("sem o cache, a falha deveria ser retornada")
+	}
+
+	CacheCardapios = true
+	fora.Store(false)
+	if _, err := ObterTodosOsCardapios(); err != nil {
+		t.Fatal(err)
+	}
+	//Uma resposta vazia (sem refeições) é retornada mesmo com os cardápios guardados, e não substitui os guardados
+	vazia.Store(true)
+	if c, err := ObterTodosOsCardapios(); !errors.Is(err, ErrNãoHáRefeições) {
+		t.Fatalf("esperava ErrNãoHáRefeições, recebeu %+v, %v", c, err)
+	}
+	vazia.Store(false)
+	fora.Store(true)
+	c, err := ObterTodosOsCardapios()
+	if err != nil {
+		t.Fatalf("deveria usar os cardápios guardados: %v", err)
+	}
+	if len(c) != 1 || c[0].Nid != "277" {
+		t.Fatalf("cardápios inesperados: %+v", c)
+	}
+
+	IdadeMaximaCacheCardapios = 0
+	if _, err := ObterTodosOsCardapios(); err == nil {
+		t.Fatal("cardápios mais velhos que IdadeMaximaCacheCardapios não deveriam ser usados")
+	}
+}
@@ -4,91 +4,67 @@ import (
 	"bytes"
 	"crypto/aes"
 	"crypto/cipher"
-	"crypto/sha1"
+	"crypto/rand"
 	"encoding/base64"
-	"encoding/hex"
-	"errors"
 	"fmt"
-	"math/rand"
-	"time"
-
-	"golang.org/x/crypto/pbkdf2"
+	"io"
 )
 
-// Gera uma palavra aleatoria de tamanho x
-func randomString(x int) string {
-	rand.Seed(time.Now().UnixNano())
-	letters := []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ")
-	s := make([]rune, x)
-	for i := range s {
-		s[i] = letters[rand.Intn(len(letters))]
+const letrasSenha = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+// Gera uma senha de x letras lidas de aleatorio (crypto/rand.Reader se nil). Os bytes que causariam viés
+// (maiores ou iguais ao maior múltiplo de len(letrasSenha) que cabe em um byte) são descartados.
+func randomString(aleatorio io.Reader, x int) (string, error) {
+	if x < 1 {
+		return "", fmt.Errorf("tamanho de senha inválido: %d", x)
+	}
+	if aleatorio == nil {
+		aleatorio = rand.Reader
+	}
+	const limite = 256 - 256%len(letrasSenha)
+	senha := make([]byte, 0, x)
+	buf := make([]byte, x+x/4+1)
+	for len(senha) < x {
+		if _, err := io.ReadFull(aleatorio, buf); err != nil {
+			return "", fmt.Errorf("falha ao gerar a senha do envelope: %w", err)
+		}
+		for _, b := range buf {
+			if int(b) < limite && len(senha) < x {
+				senha = append(senha, letrasSenha[int(b)%len(letrasSenha)])
+			}
+		}
 	}
-	return string(s)
+	return string(senha), nil
 }
 
-// Gera uma chave derivada usando o algoritmo PBKDF2 (Password-Based Key Derivation Function 2)
-func generateKeyEncryptation(salt, passphrase string, keySize, iterationCount int) ([]byte, error) {
-	saltBytes, err := hex.DecodeString(salt)
+// Criptografa o texto simples fornecido usando AES no modo CBC, retornando o resultado em base64
+func encryptAES(salt, iv, passphrase, plaintext string, keySize, iterationCount int) (string, error) {
+	ciphertext, err := encryptAESBytes(salt, iv, passphrase, []byte(plaintext), keySize, iterationCount)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
-	key := pbkdf2.Key([]byte(passphrase), saltBytes, iterationCount, keySize, sha1.New)
-	return key, nil
+	return base64.StdEncoding.EncodeToString(ciphertext), nil
 }
 
-// Criptografa o texto simples fornecido usando AES no modo CBC
-func encryptAES(salt, iv, passphrase, plaintext string, keySize, iterationCount int) (string, error) {
-	key, err := generateKeyEncryptation(salt, passphrase, keySize, iterationCount)
+// Adiciona o padding PKCS7 e criptografa os bytes usando AES no modo CBC no próprio slice.
+// O slice retornado pode ser o mesmo de plaintext, se ele tiver capacidade para o padding.
+func encryptAESBytes(salt, iv, passphrase string, plaintext []byte, keySize, iterationCount int) ([]byte, error) {
+	key, err := generateKey(salt, passphrase, keySize, iterationCount)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	ivBytes, err := hex.DecodeString(iv)
+	ivBytes, err := decodeIV(iv)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 	block, err := aes.NewCipher(key)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	plaintextBytes := []byte(plaintext)
-	padding := aes.BlockSize - len(plaintextBytes)%aes.BlockSize
-	padtext := bytes.Repeat([]byte{byte(padding)}, padding)
-	plaintextBytes = append(plaintextBytes, padtext...)
-
-	ciphertext := make([]byte, aes.BlockSize+len(plaintextBytes))
-	copy(ciphertext[:aes.BlockSize], ivBytes)
+	padding := aes.BlockSize - len(plaintext)%aes.BlockSize
+	plaintext = append(plaintext, bytes.Repeat([]byte{byte(padding)}, padding)...)
 
 	mode := cipher.NewCBCEncrypter(block, ivBytes)
-	mode.CryptBlocks(ciphertext[aes.BlockSize:], plaintextBytes)
-
-	return base64.StdEncoding.EncodeToString(ciphertext[aes.BlockSize:]), nil
-}
-
-// Criptografa o texto usando a senha
-func encryptText(text, passphrase string) (string, error) {
-	const (
-		salt      = "3FF2EC019C627B945225DEBAD71A01B6985FE84C95A70EB132882F88C0A59A55"
-		iv        = "F27D5C9927726BCEFE7510B1BDD3D137"
-		keySize   = 16
-		iterCount = 10
-	)
-	return encryptAES(salt, iv, passphrase, text, keySize, iterCount)
-}
-
-// Cria o JSON da requests com os dados encriptados
-func criptografarRequestParams(req string) (string, error) {
-	if req == "" {
-		return "", errors.New("requestParams está vazio")
-	}
-	p := randomString(25)
-	encryptedText, err := encryptText(req, p)
-	if err != nil {
-		return "", err
-	}
-	decodedSuffix, err := base64.StdEncoding.DecodeString("WWNrbjlTQUZwcU04SzlCMnVKWWVlSFpqRkho")
-	if err != nil {
-		return "", err
-	}
-	er := encryptedText + string(decodedSuffix) + p
-	return fmt.Sprintf(`{"requestParams":"%v"}`, er), nil
+	mode.CryptBlocks(plaintext, plaintext)
+	return plaintext, nil
 }
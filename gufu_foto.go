@@ -0,0 +1,154 @@
+package gufu
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif" //Registra o formato GIF para image.Decode
+	"strings"
+)
+
+var (
+	ErrFotoVazia       = errors.New("foto vazia")
+	ErrFotoInvalida    = errors.New("foto inválida")
+	ErrFotoMuitoGrande = errors.New("foto maior que o tamanho máximo permitido")
+)
+
+var (
+	TamanhoMaximoFoto  = 10 << 20 //Tamanho máximo, em bytes, de uma foto depois de decodificada do base64. Pode ser alterado para atender necessidades específicas.
+	DimensaoMaximaFoto = 4096     //Largura e altura máximas, em pixels, de uma foto. Evita alocar memória demais com imagens malformadas.
+)
+
+// DecodificarFoto decodifica o campo Foto (em base64) da identidade digital. Retorna a imagem, o formato detectado (Ex: "jpeg") e um erro.
+func (i *IdentidadeDigital) DecodificarFoto() (image.Image, string, error) {
+	return DecodificarImagemBase64(i.Foto)
+}
+
+// DecodificarFoto decodifica o campo Foto (em base64) da identidade digital. Retorna a imagem, o formato detectado (Ex: "jpeg") e um erro.
+func (i *IdUfu) DecodificarFoto() (image.Image, string, error) {
+	return DecodificarImagemBase64(i.Foto)
+}
+
+// DecodificarAvatar decodifica o campo Avatar (em base64) do login. Retorna a imagem, o formato detectado (Ex: "png") e um erro.
+func (d *DadosLoginMobile) DecodificarAvatar() (image.Image, string, error) {
+	return DecodificarImagemBase64(d.Avatar)
+}
+
+// DecodificarImagemBase64 decodifica uma imagem JPEG, PNG ou GIF em base64, com ou sem o prefixo "data:image/...;base64,".
+// Retorna a imagem, o formato detectado e um erro. Imagens maiores que TamanhoMaximoFoto ou DimensaoMaximaFoto
+// são recusadas com ErrFotoMuitoGrande antes de serem decodificadas.
+func DecodificarImagemBase64(foto string) (image.Image, string, error) {
+	foto = strings.TrimSpace(foto)
+	if strings.HasPrefix(foto, "data:") {
+		virgula := strings.IndexByte(foto, ',')
+		if virgula == -1 {
+			return nil, "", ErrFotoInvalida
+		}
+		foto = foto[virgula+1:]
+	}
+	//Remove as quebras de linha e espaços que alguns servidores inserem no base64
+	foto = strings.Map(func(r rune) rune {
+		if r == '\n' || r == '\r' || r == ' ' || r == '\t' {
+			return -1
+		}
+		return r
+	}, foto)
+	if foto == "" {
+		return nil, "", ErrFotoVazia
+	}
+	if base64.StdEncoding.DecodedLen(len(foto)) > TamanhoMaximoFoto {
+		return nil, "", ErrFotoMuitoGrande
+	}
+
+	dados, err := base64.StdEncoding.DecodeString(foto)
+	if err != nil {
+		//Tenta as variantes sem preenchimento e com o alfabeto de URLs
+		var errVariante error
+		for _, codificacao := range []*base64.Encoding{base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+			if dados, errVariante = codificacao.DecodeString(foto); errVariante == nil {
+				break
+			}
+		}
+		if errVariante != nil {
+			return nil, "", fmt.Errorf("%w: %v", ErrFotoInvalida, err)
+		}
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(dados))
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrFotoInvalida, err)
+	}
+	if config.Width <= 0 || config.Height <= 0 {
+		return nil, "", ErrFotoInvalida
+	}
+	if config.Width > DimensaoMaximaFoto || config.Height > DimensaoMaximaFoto {
+		return nil, "", ErrFotoMuitoGrande
+	}
+
+	img, formato, err := image.Decode(bytes.NewReader(dados))
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrFotoInvalida, err)
+	}
+	return img, formato, nil
+}
+
+// Redimensionar retorna uma cópia da imagem com a largura e altura informadas, sem manter a proporção.
+// Cada pixel é a média da área correspondente da imagem original, o que evita serrilhados ao reduzir fotos.
+func Redimensionar(img image.Image, largura, altura int) *image.RGBA {
+	destino := image.NewRGBA(image.Rect(0, 0, max(largura, 1), max(altura, 1)))
+	b := img.Bounds()
+	if b.Empty() {
+		return destino
+	}
+	origem, ok := img.(*image.RGBA)
+	if !ok || b.Min != (image.Point{}) {
+		origem = image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+		draw.Draw(origem, origem.Bounds(), img, b.Min, draw.Src)
+	}
+
+	lo, ao := origem.Rect.Dx(), origem.Rect.Dy()
+	ld, ad := destino.Rect.Dx(), destino.Rect.Dy()
+	for y := 0; y < ad; y++ {
+		y0 := y * ao / ad
+		y1 := max((y+1)*ao/ad, y0+1)
+		for x := 0; x < ld; x++ {
+			x0 := x * lo / ld
+			x1 := max((x+1)*lo/ld, x0+1)
+			var soma [4]int
+			for sy := y0; sy < y1; sy++ {
+				linha := origem.Pix[sy*origem.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					for c := 0; c < 4; c++ {
+						soma[c] += int(linha[sx*4+c])
+					}
+				}
+			}
+			n := (y1 - y0) * (x1 - x0)
+			i := destino.PixOffset(x, y)
+			for c := 0; c < 4; c++ {
+				destino.Pix[i+c] = uint8(soma[c] / n)
+			}
+		}
+	}
+	return destino
+}
+
+// Miniatura reduz a imagem para caber em um quadrado de lado pixels, mantendo a proporção.
+// Imagens que já cabem no quadrado são apenas copiadas.
+func Miniatura(img image.Image, lado int) *image.RGBA {
+	b := img.Bounds()
+	largura, altura := b.Dx(), b.Dy()
+	if largura > lado || altura > lado {
+		if largura >= altura {
+			altura = max(altura*lado/largura, 1)
+			largura = lado
+		} else {
+			largura = max(largura*lado/altura, 1)
+			altura = lado
+		}
+	}
+	return Redimensionar(img, largura, altura)
+}
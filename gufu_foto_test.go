@@ -0,0 +1,101 @@
+package gufu
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func fotoBase64(t *testing.T, largura, altura int, codificar func(*bytes.Buffer, image.Image) error) string {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, largura, altura))
+	for y := 0; y < altura; y++ {
+		for x := 0; x < largura; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 0x80, A: 0xff})
+		}
+	}
+	var buf bytes.Buffer
+	if err := codificar(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+func TestDecodificarFoto(t *testing.T) {
+	foto := fotoBase64(t, 120, 160, func(b *bytes.Buffer, img image.Image) error { return jpeg.Encode(b, img, nil) })
+	id := IdentidadeDigital{Foto: foto}
+	img, formato, err := id.DecodificarFoto()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if formato != "jpeg" || img.Bounds().Dx() != 120 || img.Bounds().Dy() != 160 {
+		t.Fatalf("foto inesperada: %v %v", formato, img.Bounds())
+	}
+
+	avatar := DadosLoginMobile{Avatar: "data:image/png;base64," + fotoBase64(t, 10, 10, func(b *bytes.Buffer, img image.Image) error { return png.Encode(b, img) })}
+	if _, formato, err := avatar.DecodificarAvatar(); err != nil || formato != "png" {
+		t.Fatalf("avatar com prefixo data: deveria ser aceito: %v %v", formato, err)
+	}
+}
+
+func TestDecodificarFotoInvalida(t *testing.T) {
+	casos := []struct {
+		foto string
+		erro error
+	}{
+		{"", ErrFotoVazia},
+		{"  \n", ErrFotoVazia},
+		{"não é base64!", ErrFotoInvalida},
+		{base64.StdEncoding.EncodeToString([]byte("texto qualquer")), ErrFotoInvalida},
+		{"data:image/png;base64", ErrFotoInvalida},
+	}
+	for _, c := range casos {
+		if _, _, err := (&IdUfu{Foto: c.foto}).DecodificarFoto(); !errors.Is(err, c.erro) {
+			t.Errorf("%q: esperado %v, obtido %v", c.foto, c.erro, err)
+		}
+	}
+}
+
+func TestDecodificarFotoMuitoGrande(t *testing.T) {
+	foto := fotoBase64(t, 300, 20, func(b *bytes.Buffer, img image.Image) error { return png.Encode(b, img) })
+
+	dimensao := DimensaoMaximaFoto
+	DimensaoMaximaFoto = 200
+	_, _, err := DecodificarImagemBase64(foto)
+	DimensaoMaximaFoto = dimensao
+	if !errors.Is(err, ErrFotoMuitoGrande) {
+		t.Fatalf("esperado ErrFotoMuitoGrande pela dimensão, obtido %v", err)
+	}
+
+	tamanho := TamanhoMaximoFoto
+	TamanhoMaximoFoto = 100
+	_, _, err = DecodificarImagemBase64(foto)
+	TamanhoMaximoFoto = tamanho
+	if !errors.Is(err, ErrFotoMuitoGrande) {
+		t.Fatalf("esperado ErrFotoMuitoGrande pelo tamanho, obtido %v", err)
+	}
+}
+
+func TestMiniatura(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 400, 200))
+	if b := Miniatura(img, 100).Bounds(); b.Dx() != 100 || b.Dy() != 50 {
+		t.Fatalf("miniatura deveria manter a proporção, obtido %v", b)
+	}
+	if b := Miniatura(image.NewGray(image.Rect(10, 10, 60, 110)), 200).Bounds(); b.Dx() != 50 || b.Dy() != 100 {
+		t.Fatalf("imagens menores não deveriam ser ampliadas, obtido %v", b)
+	}
+
+	//Uma imagem de cor única continua com a mesma cor depois de reduzida
+	cor := color.RGBA{R: 10, G: 20, B: 30, A: 0xff}
+	cheia := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	draw.Draw(cheia, cheia.Bounds(), image.NewUniform(cor), image.Point{}, draw.Src)
+	if c := Redimensionar(cheia, 7, 5).RGBAAt(6, 4); c != cor {
+		t.Fatalf("cor inesperada depois de reduzir: %v", c)
+	}
+}
@@ -0,0 +1,368 @@
+package gufu
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Tipos do formato HAR 1.2 (HTTP Archive), usado pelos navegadores e proxies para guardar capturas de tráfego.
+// São usados pelo comando "gufu proxy" para registrar o tráfego do aplicativo com os corpos descriptografados
+// e por DescriptografarHAR e CriptografarHAR para processar capturas já existentes.
+// Apenas os campos usados pela biblioteca e pelas ferramentas comuns estão presentes.
+
+// HAR é o documento raiz de um arquivo HAR.
+type HAR struct {
+	Log LogHAR `json:"log"`
+}
+
+// LogHAR contém as requisições capturadas.
+type LogHAR struct {
+	Version string       `json:"version"`
+	Creator CriadorHAR   `json:"creator"`
+	Entries []EntradaHAR `json:"entries"`
+	Comment string       `json:"comment,omitempty"`
+}
+
+// CriadorHAR identifica o programa que gerou o arquivo.
+type CriadorHAR struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// EntradaHAR é uma requisição e a sua resposta.
+type EntradaHAR struct {
+	StartedDateTime time.Time     `json:"startedDateTime"`
+	Time            float64       `json:"time"` //Duração total em milissegundos
+	Request         RequisicaoHAR `json:"request"`
+	Response        RespostaHAR   `json:"response"`
+	Cache           struct{}      `json:"cache"`
+	Timings         TemposHAR     `json:"timings"`
+	ServerIPAddress string        `json:"serverIPAddress,omitempty"`
+	Comment         string        `json:"comment,omitempty"`
+}
+
+// RequisicaoHAR é a requisição de uma EntradaHAR.
+type RequisicaoHAR struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []CookieHAR    `json:"cookies"`
+	Headers     []CabecalhoHAR `json:"headers"`
+	QueryString []CabecalhoHAR `json:"queryString"`
+	PostData    *DadosPostHAR  `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+	Comment     string         `json:"comment,omitempty"`
+}
+
+// RespostaHAR é a resposta de uma EntradaHAR.
+type RespostaHAR struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []CookieHAR    `json:"cookies"`
+	Headers     []CabecalhoHAR `json:"headers"`
+	Content     ConteudoHAR    `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+	Comment     string         `json:"comment,omitempty"`
+}
+
+// CabecalhoHAR é um cabeçalho HTTP ou um parâmetro da query.
+type CabecalhoHAR struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// CookieHAR é um cookie enviado ou recebido.
+type CookieHAR struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// DadosPostHAR é o corpo de uma requisição.
+type DadosPostHAR struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+// ConteudoHAR é o corpo de uma resposta. Encoding é "base64" quando Text está em base64 (Ex: imagens).
+type ConteudoHAR struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text,omitempty"`
+	Encoding string `json:"encoding,omitempty"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+// TemposHAR contém os tempos de uma requisição em milissegundos. -1 indica que o tempo não se aplica.
+type TemposHAR struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+// Marca colocada no comentário dos corpos (DadosPostHAR e ConteudoHAR) que foram descriptografados pelo gufu.
+// Se o corpo já tiver um comentário, a marca é acrescentada entre parênteses no final (Ex: "login (descriptografado
+// pelo gufu)") e apenas ela é removida quando o corpo é criptografado de novo.
+const ComentarioHARDescriptografado = "descriptografado pelo gufu"
+
+// Acrescenta a marca ComentarioHARDescriptografado ao comentário
+func marcarDescriptografado(comentario string) string {
+	if comentario == "" {
+		return ComentarioHARDescriptografado
+	}
+	return comentario + " (" + ComentarioHARDescriptografado + ")"
+}
+
+// Informa se o comentário tem a marca ComentarioHARDescriptografado
+func descriptografadoPeloGufu(comentario string) bool {
+	_, ok := desmarcarDescriptografado(comentario)
+	return ok
+}
+
+// Retorna o comentário sem a marca ComentarioHARDescriptografado e se ela estava presente
+func desmarcarDescriptografado(comentario string) (string, bool) {
+	if comentario == ComentarioHARDescriptografado {
+		return "", true
+	}
+	return strings.CutSuffix(comentario, " ("+ComentarioHARDescriptografado+")")
+}
+
+// NovoHAR retorna um HAR 1.2 vazio criado pelo gufu.
+func NovoHAR() *HAR {
+	return &HAR{Log: LogHAR{Version: "1.2", Creator: CriadorHAR{Name: "gufu", Version: "1"}, Entries: []EntradaHAR{}}}
+}
+
+// ResumoHAR informa quantos corpos de um HAR foram descriptografados ou criptografados.
+type ResumoHAR struct {
+	Requisicoes int //Corpos de requisições alterados
+	Respostas   int //Corpos de respostas alterados
+}
+
+// DescriptografarHAR retorna uma cópia do HAR em que os corpos das requisições ({"requestParams": ...}) e das
+// respostas da API do aplicativo estão descriptografados, como JSON formatado, com o comentário
+// ComentarioHARDescriptografado. Os demais corpos e campos não são alterados, então capturas de outros sistemas podem
+// ser processadas sem problemas. Usa o CodecPadrao.
+func DescriptografarHAR(h *HAR) (*HAR, ResumoHAR) {
+	copia, resumo := copiarHAR(h), ResumoHAR{}
+	for i := range copia.Log.Entries {
+		requisicao, resposta := DescriptografarEntradaHAR(&copia.Log.Entries[i])
+		if requisicao {
+			resumo.Requisicoes++
+		}
+		if resposta {
+			resumo.Respostas++
+		}
+	}
+	return copia, resumo
+}
+
+// CriptografarHAR faz o inverso de DescriptografarHAR: retorna uma cópia do HAR em que os corpos com o comentário
+// ComentarioHARDescriptografado são criptografados de novo, com uma nova senha, e o comentário é removido.
+// Útil para usar capturas revisadas ou editadas como respostas de um emulador da API.
+func CriptografarHAR(h *HAR) (*HAR, ResumoHAR, error) {
+	copia, resumo := copiarHAR(h), ResumoHAR{}
+	for i := range copia.Log.Entries {
+		requisicao, resposta, err := CriptografarEntradaHAR(&copia.Log.Entries[i])
+		if err != nil {
+			return nil, resumo, fmt.Errorf("entrada %d (%s): %w", i, copia.Log.Entries[i].Request.URL, err)
+		}
+		if requisicao {
+			resumo.Requisicoes++
+		}
+		if resposta {
+			resumo.Respostas++
+		}
+	}
+	return copia, resumo, nil
+}
+
+// DescriptografarEntradaHAR descriptografa, na própria entrada, os corpos da requisição e da resposta que forem
+// envelopes da API do aplicativo. Retorna quais corpos foram descriptografados.
+// Os tamanhos (bodySize e content.size) passam a ser os dos corpos descriptografados e as respostas que estavam
+// em base64 recebem o mimeType application/json.
+func DescriptografarEntradaHAR(e *EntradaHAR) (requisicao, resposta bool) {
+	if p := e.Request.PostData; p != nil && !descriptografadoPeloGufu(p.Comment) {
+		if texto, err := DescriptografarRequisicao(p.Text); err == nil {
+			p.Text, p.Comment, requisicao = indentarJSON(texto), marcarDescriptografado(p.Comment), true
+			e.Request.BodySize = len(p.Text)
+		}
+	}
+	if c := &e.Response.Content; !descriptografadoPeloGufu(c.Comment) && c.Text != "" {
+		corpo := c.Text
+		if c.Encoding == "base64" {
+			decodificado, err := base64.StdEncoding.DecodeString(corpo)
+			if err != nil {
+				return requisicao, false
+			}
+			corpo = string(decodificado)
+		}
+		if texto, err := Descriptografar(corpo); err == nil {
+			if c.Encoding == "base64" {
+				c.MimeType = "application/json"
+			}
+			c.Text, c.Encoding, c.Comment, resposta = indentarJSON(texto), "", marcarDescriptografado(c.Comment), true
+			c.Size, e.Response.BodySize = len(c.Text), len(c.Text)
+		}
+	}
+	return requisicao, resposta
+}
+
+// CriptografarEntradaHAR criptografa, na própria entrada, os corpos marcados com ComentarioHARDescriptografado,
+// removendo a marca e mantendo o restante do comentário. Retorna quais corpos foram criptografados.
+// Os tamanhos passam a ser os dos corpos criptografados.
+func CriptografarEntradaHAR(e *EntradaHAR) (requisicao, resposta bool, err error) {
+	if p := e.Request.PostData; p != nil && descriptografadoPeloGufu(p.Comment) {
+		texto, err := Criptografar(compactarJSON(p.Text))
+		if err != nil {
+			return false, false, err
+		}
+		p.Comment, _ = desmarcarDescriptografado(p.Comment)
+		p.Text, requisicao = texto, true
+		e.Request.BodySize = len(p.Text)
+	}
+	if c := &e.Response.Content; descriptografadoPeloGufu(c.Comment) {
+		texto, err := CriptografarResposta(compactarJSON(c.Text))
+		if err != nil {
+			return requisicao, false, err
+		}
+		c.Comment, _ = desmarcarDescriptografado(c.Comment)
+		c.Text, resposta = texto, true
+		c.Size, e.Response.BodySize = len(c.Text), len(c.Text)
+	}
+	return requisicao, resposta, nil
+}
+
+var ErrHARInvalido = errors.New("o arquivo não é um HAR: log.entries não foi encontrado") //Erro retornado por DescriptografarArquivoHAR e CriptografarArquivoHAR.
+
+// DescriptografarArquivoHAR é a versão de DescriptografarHAR para o conteúdo de um arquivo HAR. Apenas os corpos são
+// alterados: campos que não existem em HAR, como pages, os tempos detalhados e os campos personalizados ("_..."),
+// são mantidos. Retorna ErrHARInvalido se dados não for um HAR.
+func DescriptografarArquivoHAR(dados []byte) ([]byte, ResumoHAR, error) {
+	return processarArquivoHAR(dados, func(e *EntradaHAR) (bool, bool, error) {
+		requisicao, resposta := DescriptografarEntradaHAR(e)
+		return requisicao, resposta, nil
+	})
+}
+
+// CriptografarArquivoHAR é a versão de CriptografarHAR para o conteúdo de um arquivo HAR, mantendo os demais campos
+// como DescriptografarArquivoHAR. Retorna ErrHARInvalido se dados não for um HAR.
+func CriptografarArquivoHAR(dados []byte) ([]byte, ResumoHAR, error) {
+	return processarArquivoHAR(dados, CriptografarEntradaHAR)
+}
+
+// Aplica processar aos corpos de cada entrada do HAR, sem converter o arquivo para os tipos HAR,
+// para que os campos desconhecidos não sejam perdidos
+func processarArquivoHAR(dados []byte, processar func(e *EntradaHAR) (bool, bool, error)) ([]byte, ResumoHAR, error) {
+	var raiz map[string]any
+	decodificador := json.NewDecoder(bytes.NewReader(dados))
+	decodificador.UseNumber()
+	if err := decodificador.Decode(&raiz); err != nil {
+		return nil, ResumoHAR{}, fmt.Errorf("%w: %v", ErrHARInvalido, err)
+	}
+	log, _ := raiz["log"].(map[string]any)
+	entradas, ok := log["entries"].([]any)
+	if !ok {
+		return nil, ResumoHAR{}, ErrHARInvalido
+	}
+
+	resumo := ResumoHAR{}
+	for i, item := range entradas {
+		entrada, _ := item.(map[string]any)
+		requisicao, _ := entrada["request"].(map[string]any)
+		resposta, _ := entrada["response"].(map[string]any)
+		dadosPost, _ := requisicao["postData"].(map[string]any)
+		conteudo, _ := resposta["content"].(map[string]any)
+
+		var e EntradaHAR
+		if dadosPost != nil {
+			e.Request.PostData = &DadosPostHAR{Text: textoJSON(dadosPost, "text"), Comment: textoJSON(dadosPost, "comment")}
+		}
+		if conteudo != nil {
+			e.Response.Content = ConteudoHAR{MimeType: textoJSON(conteudo, "mimeType"), Text: textoJSON(conteudo, "text"), Encoding: textoJSON(conteudo, "encoding"), Comment: textoJSON(conteudo, "comment")}
+		}
+		alterouRequisicao, alterouResposta, err := processar(&e)
+		if err != nil {
+			return nil, resumo, fmt.Errorf("entrada %d (%v): %w", i, requisicao["url"], err)
+		}
+		if alterouRequisicao {
+			definirTextoJSON(dadosPost, "text", e.Request.PostData.Text)
+			definirTextoJSON(dadosPost, "comment", e.Request.PostData.Comment)
+			requisicao["bodySize"] = e.Request.BodySize
+			resumo.Requisicoes++
+		}
+		if alterouResposta {
+			definirTextoJSON(conteudo, "text", e.Response.Content.Text)
+			definirTextoJSON(conteudo, "encoding", e.Response.Content.Encoding)
+			definirTextoJSON(conteudo, "comment", e.Response.Content.Comment)
+			if e.Response.Content.MimeType != "" {
+				conteudo["mimeType"] = e.Response.Content.MimeType
+			}
+			conteudo["size"] = e.Response.Content.Size
+			resposta["bodySize"] = e.Response.BodySize
+			resumo.Respostas++
+		}
+	}
+	var saida bytes.Buffer
+	codificador := json.NewEncoder(&saida)
+	codificador.SetEscapeHTML(false)
+	codificador.SetIndent("", "  ")
+	if err := codificador.Encode(raiz); err != nil {
+		return nil, resumo, err
+	}
+	return saida.Bytes(), resumo, nil
+}
+
+func textoJSON(objeto map[string]any, chave string) string {
+	texto, _ := objeto[chave].(string)
+	return texto
+}
+
+// Define o campo, ou o remove se o valor for vazio
+func definirTextoJSON(objeto map[string]any, chave, valor string) {
+	if valor == "" {
+		delete(objeto, chave)
+		return
+	}
+	objeto[chave] = valor
+}
+
+// Copia o HAR, incluindo as entradas e os corpos, para que o original não seja alterado
+func copiarHAR(h *HAR) *HAR {
+	copia := *h
+	copia.Log.Entries = make([]EntradaHAR, len(h.Log.Entries))
+	for i, e := range h.Log.Entries {
+		if e.Request.PostData != nil {
+			dados := *e.Request.PostData
+			e.Request.PostData = &dados
+		}
+		copia.Log.Entries[i] = e
+	}
+	return &copia
+}
+
+// Formata um JSON com indentação, ou retorna o texto sem alterações se ele não for um JSON
+func indentarJSON(texto string) string {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, []byte(texto), "", "  "); err != nil {
+		return texto
+	}
+	return buf.String()
+}
+
+// Remove a formatação de um JSON, ou retorna o texto sem alterações se ele não for um JSON
+func compactarJSON(texto string) string {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, []byte(texto)); err != nil {
+		return texto
+	}
+	return buf.String()
+}
@@ -0,0 +1,149 @@
+package gufu
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func harDeTeste(t *testing.T) *HAR {
+	t.Helper()
+	requisicao, err := Criptografar(`{"login":"fulano"}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resposta := respostaCriptografada(t, `{"nome":"Fulano"}`)
+	har := NovoHAR()
+	har.Log.Entries = []EntradaHAR{
+		{
+			Request:  RequisicaoHAR{Method: "POST", URL: "https://www.sistemas.ufu.br/mobile-gateway/autenticacao/autenticarV2", PostData: &DadosPostHAR{MimeType: "application/json", Text: requisicao}},
+			Response: RespostaHAR{Status: 200, Content: ConteudoHAR{Size: len(resposta), Text: resposta}},
+		},
+		{
+			Request:  RequisicaoHAR{Method: "GET", URL: "https://www.sistemas.ufu.br/mobile-gateway/api/cardapios/"},
+			Response: RespostaHAR{Status: 200, Content: ConteudoHAR{Size: len(resposta), MimeType: "application/octet-stream", Text: base64.StdEncoding.EncodeToString([]byte(resposta)), Encoding: "base64", Comment: "cardápio do dia"}, BodySize: len(resposta)},
+		},
+		{
+			Request:  RequisicaoHAR{Method: "POST", URL: "https://exemplo.com/", PostData: &DadosPostHAR{Text: `{"a":1}`}},
+			Response: RespostaHAR{Status: 200, Content: ConteudoHAR{Text: "<html></html>"}},
+		},
+	}
+	return har
+}
+
+// Confere se os tamanhos dos corpos das entradas correspondem aos textos
+func conferirTamanhosHAR(t *testing.T, entradas ...EntradaHAR) {
+	t.Helper()
+	for _, e := range entradas {
+		if p := e.Request.PostData; p != nil && e.Request.BodySize != len(p.Text) {
+			t.Errorf("%s: bodySize da requisição = %d, esperava %d", e.Request.URL, e.Request.BodySize, len(p.Text))
+		}
+		if c := e.Response.Content; c.Size != len(c.Text) || e.Response.BodySize != len(c.Text) {
+			t.Errorf("%s: size = %d e bodySize = %d, esperava %d", e.Request.URL, c.Size, e.Response.BodySize, len(c.Text))
+		}
+	}
+}
+
+func TestDescriptografarHAR(t *testing.T) {
+	original := harDeTeste(t)
+	textoOriginal := original.Log.Entries[0].Request.PostData.Text
+
+	har, resumo := DescriptografarHAR(original)
+	if resumo != (ResumoHAR{Requisicoes: 1, Respostas: 2}) {
+		t.Fatalf("resumo inesperado: %+v", resumo)
+	}
+	if original.Log.Entries[0].Request.PostData.Text != textoOriginal {
+		t.Fatal("o HAR original não deveria ser alterado")
+	}
+	e := har.Log.Entries[0]
+	if e.Request.PostData.Text != "{\n  \"login\": \"fulano\"\n}" || e.Request.PostData.Comment != ComentarioHARDescriptografado {
+		t.Fatalf("requisição inesperada: %+v", e.Request.PostData)
+	}
+	if c := har.Log.Entries[1].Response.Content; c.Text != "{\n  \"nome\": \"Fulano\"\n}" || c.Encoding != "" || c.MimeType != "application/json" || c.Comment != "cardápio do dia ("+ComentarioHARDescriptografado+")" {
+		t.Fatalf("resposta em base64 inesperada: %+v", c)
+	}
+	conferirTamanhosHAR(t, har.Log.Entries[:2]...)
+	if outro := har.Log.Entries[2]; outro.Request.PostData.Comment != "" || outro.Response.Content.Text != "<html></html>" {
+		t.Fatalf("corpos que não são envelopes não deveriam ser alterados: %+v", outro)
+	}
+	if _, resumo := DescriptografarHAR(har); resumo != (ResumoHAR{}) {
+		t.Fatalf("corpos já descriptografados não deveriam ser alterados: %+v", resumo)
+	}
+
+	criptografado, resumo, err := CriptografarHAR(har)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resumo != (ResumoHAR{Requisicoes: 1, Respostas: 2}) {
+		t.Fatalf("resumo inesperado: %+v", resumo)
+	}
+	conferirTamanhosHAR(t, criptografado.Log.Entries[:2]...)
+	if texto, err := DescriptografarRequisicao(criptografado.Log.Entries[0].Request.PostData.Text); err != nil || texto != `{"login":"fulano"}` {
+		t.Fatalf("DescriptografarRequisicao = %q, %v", texto, err)
+	}
+	if texto, err := Descriptografar(criptografado.Log.Entries[1].Response.Content.Text); err != nil || texto != `{"nome":"Fulano"}` {
+		t.Fatalf("Descriptografar = %q, %v", texto, err)
+	}
+	//Apenas a marca é removida do comentário do usuário
+	if criptografado.Log.Entries[0].Request.PostData.Comment != "" || criptografado.Log.Entries[1].Response.Content.Comment != "cardápio do dia" {
+		t.Fatalf("comentários inesperados: %+v", criptografado.Log.Entries[:2])
+	}
+}
+
+func TestDescriptografarArquivoHAR(t *testing.T) {
+	dados, err := json.Marshal(harDeTeste(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	//Campos que não existem nos tipos HAR precisam ser mantidos
+	dados = []byte(strings.Replace(string(dados), `"entries":[`, `"pages":[{"id":"pagina_1"}],"entries":[{"_resourceType":"xhr","request":{"method":"GET","url":"https://exemplo.com/x"},"response":{"status":204,"content":{"size":0,"compression":0}},"time":1234567890123456789},`, 1))
+
+	descriptografado, resumo, err := DescriptografarArquivoHAR(dados)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resumo != (ResumoHAR{Requisicoes: 1, Respostas: 2}) {
+		t.Fatalf("resumo inesperado: %+v", resumo)
+	}
+	for _, esperado := range []string{`"<html></html>"`, `"pages"`, `"_resourceType": "xhr"`, `"compression": 0`, `1234567890123456789`, ComentarioHARDescriptografado} {
+		if !strings.Contains(string(descriptografado), esperado) {
+			t.Errorf("o HAR descriptografado deveria conter %s", esperado)
+		}
+	}
+
+	var har HAR
+	if err := json.Unmarshal(descriptografado, &har); err != nil {
+		t.Fatal(err)
+	}
+	conferirTamanhosHAR(t, har.Log.Entries[1:3]...)
+	if har.Log.Entries[2].Response.Content.MimeType != "application/json" {
+		t.Errorf("a resposta em base64 deveria passar a ter o mimeType application/json: %+v", har.Log.Entries[2].Response.Content)
+	}
+
+	criptografado, resumo, err := CriptografarArquivoHAR(descriptografado)
+	if err != nil {
+		t.Fatal(err)
+	}
+	har = HAR{}
+	if err := json.Unmarshal(criptografado, &har); err != nil {
+		t.Fatal(err)
+	}
+	conferirTamanhosHAR(t, har.Log.Entries[1:3]...)
+	if resumo.Requisicoes != 1 || har.Log.Entries[1].Request.PostData.Comment != "" || strings.Contains(string(criptografado), ComentarioHARDescriptografado) {
+		t.Fatalf("os corpos deveriam ser criptografados de novo: %+v", resumo)
+	}
+	if texto, err := Descriptografar(har.Log.Entries[2].Response.Content.Text); err != nil || texto != `{"nome":"Fulano"}` {
+		t.Fatalf("Descriptografar = %q, %v", texto, err)
+	}
+	if har.Log.Entries[2].Response.Content.Comment != "cardápio do dia" {
+		t.Fatalf("o comentário do usuário deveria ser mantido: %+v", har.Log.Entries[2].Response.Content)
+	}
+
+	for _, invalido := range []string{"não é json", `{"log":{}}`, `[]`} {
+		if _, _, err := DescriptografarArquivoHAR([]byte(invalido)); !errors.Is(err, ErrHARInvalido) {
+			t.Errorf("DescriptografarArquivoHAR(%q) = %v, esperava ErrHARInvalido", invalido, err)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package gufu
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+
+	"github.com/paemuri/brdoc"
+)
+
+var (
+	ErrIdUfuVazio       = errors.New("id ufu vazio")
+	ErrIdUfuNaoNumerico = errors.New("id ufu deve conter apenas dígitos")
+	ErrUrlIdUfuInvalida = errors.New("url não aponta para uma identidade digital da ufu")
+)
+
+var validaUfuUrl = "https://www.sistemas.ufu.br/valida-ufu" //URL Base da página de validação das identidades digitais.
+
+const (
+	tamanhoIdUfu        = 14            //Quantidade de dígitos de um id ufu: 3 de prefixo e 11 do CPF
+	tamanhoPrefixoIdUfu = 3             //Quantidade de dígitos do prefixo do id ufu
+	caminhoIdDigitalUfu = "id-digital/" //Trecho da URL do valida-ufu que antecede o id (Ex: /#/id-digital/12352998224725)
+)
+
+// IdDigitalRef é a referência para uma identidade digital da UFU, já normalizada e validada. É retornado na função InterpretarIdDigital.
+type IdDigitalRef struct {
+	Id      string //Id completo, com 14 dígitos (Ex: "12352998224725")
+	Prefixo string //Os 3 primeiros dígitos do id
+	Cpf     string //CPF do portador da identidade, os 11 últimos dígitos do id
+}
+
+// IdCompleto retorna o id da identidade digital sem máscara, incluindo o CPF. String, GoString e LogValue
+// mostram o id mascarado (veja MascararIdUfu), para que ele não vaze em logs.
+func (r IdDigitalRef) IdCompleto() string {
+	return r.Id
+}
+
+// URL retorna o endereço da página do valida-ufu para a identidade digital, o mesmo presente no QR Code.
+func (r IdDigitalRef) URL() string {
+	return validaUfuUrl + "/#/" + caminhoIdDigitalUfu + r.Id
+}
+
+// InterpretarIdDigital normaliza e valida uma referência a uma identidade digital. Retorna um ponteiro para IdDigitalRef e um erro.
+// A entrada pode ser o id puro (Ex: "12352998224725"), a URL do valida-ufu
+// (Ex: "https://www.sistemas.ufu.br/valida-ufu/#/id-digital/12352998224725") ou o conteúdo do QR Code, presente em CodigoBarra.
+func InterpretarIdDigital(entrada string) (*IdDigitalRef, error) {
+	entrada = strings.TrimSpace(entrada)
+	if entrada == "" {
+		return nil, ErrIdUfuVazio
+	}
+
+	id := entrada
+	if strings.Contains(entrada, "/") {
+		var err error
+		id, err = extrairIdDaUrl(entrada)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, c := range id {
+		if c < '0' || c > '9' {
+			return nil, ErrIdUfuNaoNumerico
+		}
+	}
+	if len(id) != tamanhoIdUfu {
+		return nil, ErrIdUfuTamanhoInvalido
+	}
+
+	ref := IdDigitalRef{
+		Id:      id,
+		Prefixo: id[:tamanhoPrefixoIdUfu],
+		Cpf:     id[tamanhoPrefixoIdUfu:],
+	}
+	if !brdoc.IsCPF(ref.Cpf) {
+		return nil, ErrCpfInvalido
+	}
+	return &ref, nil
+}
+
+// Extrai o id de uma URL do valida-ufu. O id pode estar no fragmento (/#/id-digital/ID),
+// no caminho (/id-digital/ID) ou no parâmetro idIdentidade, usado pela API.
+func extrairIdDaUrl(entrada string) (string, error) {
+	if !strings.Contains(entrada, "://") {
+		entrada = "https://" + entrada
+	}
+	u, err := url.Parse(entrada)
+	if err != nil {
+		return "", ErrUrlIdUfuInvalida
+	}
+	host := strings.ToLower(u.Hostname())
+	if host != "ufu.br" && !strings.HasSuffix(host, ".ufu.br") {
+		return "", ErrUrlIdUfuInvalida
+	}
+
+	if id := u.Query().Get("idIdentidade"); id != "" {
+		return id, nil
+	}
+	for _, trecho := range []string{u.Fragment, u.Path} {
+		if i := strings.LastIndex(trecho, caminhoIdDigitalUfu); i != -1 {
+			id := strings.Trim(trecho[i+len(caminhoIdDigitalUfu):], "/")
+			if id == "" {
+				return "", ErrIdUfuVazio
+			}
+			return id, nil
+		}
+	}
+	return "", ErrUrlIdUfuInvalida
+}
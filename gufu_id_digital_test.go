@@ -0,0 +1,55 @@
+package gufu
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestInterpretarIdDigital(t *testing.T) {
+	const id = "12352998224725"
+	casos := []struct {
+		entrada string
+		erro    error
+	}{
+		{id, nil},
+		{"  " + id + "\n", nil},
+		{"https://www.sistemas.ufu.br/valida-ufu/#/id-digital/" + id, nil},
+		{"https://www.sistemas.ufu.br/valida-ufu/#/id-digital/" + id + "/", nil},
+		{"www.sistemas.ufu.br/valida-ufu/#/id-digital/" + id, nil},
+		{"https://www.sistemas.ufu.br/valida-gateway/id-digital/buscarDadosIdDigital?idIdentidade=" + id, nil},
+		{"", ErrIdUfuVazio},
+		{"https://www.sistemas.ufu.br/valida-ufu/#/id-digital/", ErrIdUfuVazio},
+		{"https://exemplo.com/valida-ufu/#/id-digital/" + id, ErrUrlIdUfuInvalida},
+		{"https://www.sistemas.ufu.br/valida-ufu/", ErrUrlIdUfuInvalida},
+		{"123529982247a5", ErrIdUfuNaoNumerico},
+		{"123123456789", ErrIdUfuTamanhoInvalido},
+		{"12352998224700", ErrCpfInvalido},
+	}
+	for _, c := range casos {
+		ref, err := InterpretarIdDigital(c.entrada)
+		if !errors.Is(err, c.erro) {
+			t.Errorf("%q: erro esperado %v, obtido %v", c.entrada, c.erro, err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if ref.Id != id || ref.Prefixo != "123" || ref.Cpf != "52998224725" {
+			t.Errorf("%q: referência inesperada %+v", c.entrada, ref)
+		}
+	}
+}
+
+func TestIdDigitalRefURL(t *testing.T) {
+	ref, err := InterpretarIdDigital("12352998224725")
+	if err != nil {
+		t.Fatal(err)
+	}
+	outra, err := InterpretarIdDigital(ref.URL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *outra != *ref {
+		t.Fatalf("a URL gerada deveria apontar para o mesmo id: %+v != %+v", outra, ref)
+	}
+}
@@ -0,0 +1,156 @@
+package gufu
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrIdentidadeInativa = errors.New("identidade digital não está ativa")
+	ErrIdentidadeVencida = errors.New("identidade digital vencida")
+)
+
+// SituacaoIdentidade é a situação de uma identidade digital, no formato numérico usado pelas APIs da UFU.
+// Apenas o código 3 (ativa) foi confirmado nas respostas das APIs, então os outros códigos não têm constantes
+// para não adivinhar o seu significado. Um código sem constante (veja Conhecida) é sempre tratado como uma situação
+// não ativa: String retorna "Situação N" e IsValida retorna ErrIdentidadeInativa com o SituacaoDescricao retornado
+// pela API (ou "Situação N", se a descrição estiver vazia).
+type SituacaoIdentidade int
+
+const (
+	SituacaoDesconhecida SituacaoIdentidade = 0 //Situação ausente ou em um formato não reconhecido
+	SituacaoAtiva        SituacaoIdentidade = 3 //Identidade digital ativa
+)
+
+// String retorna o nome da situação (Ex: "Ativa"). Códigos não documentados retornam "Situação N".
+func (s SituacaoIdentidade) String() string {
+	switch s {
+	case SituacaoDesconhecida:
+		return "Desconhecida"
+	case SituacaoAtiva:
+		return "Ativa"
+	}
+	return "Situação " + strconv.Itoa(int(s))
+}
+
+// Conhecida informa se a situação é uma das constantes SituacaoIdentidade. SituacaoDesconhecida também é conhecida:
+// ela indica que a API não informou a situação ou que ela não é numérica.
+func (s SituacaoIdentidade) Conhecida() bool {
+	return s == SituacaoDesconhecida || s == SituacaoAtiva
+}
+
+// Ativa informa se a situação é SituacaoAtiva.
+func (s SituacaoIdentidade) Ativa() bool {
+	return s == SituacaoAtiva
+}
+
+var fusoHorarioUfu = time.FixedZone("BRT", -3*60*60) //Fuso horário de Uberlândia, usado nas datas que a API retorna como timestamp
+
+// Identidade é o modelo único de uma identidade digital da UFU, independente de onde os dados vieram.
+// Pode ser obtida a partir de IdUfu (validação pública, ObterIdUfu) ou de IdentidadeDigital (aplicativo, BuscarIdentidadeDigital).
+type Identidade struct {
+	ID                string             //ID da identidade digital
+	Matricula         string             //Matrícula do aluno
+	Nome              string             //Nome do aluno
+	NomePai           string             //Nome do pai do aluno. Pode ser vazio
+	NomeMae           string             //Nome da mãe do aluno
+	Rg                string             //RG do aluno
+	OrgaoEmissor      string             //Órgão emissor do RG
+	Cpf               string             //CPF do aluno
+	Naturalidade      string             //Naturalidade do aluno
+	Vinculo           string             //Vínculo (aluno, servidor, etc)
+	Informacao        string             //Curso, tipo de curso e turno (Ex: "Graduação em Sistemas de Informação: Bacharelado - Noturno")
+	CodigoBarra       string             //Dado do QR Code da identidade digital
+	Foto              string             //Foto em base64
+	Situacao          SituacaoIdentidade //Situação da identidade digital
+	SituacaoDescricao string             //Descrição da situação, como retornada pela API
+	DataNascimento    time.Time          //Data de nascimento do aluno. Zero se não informada
+	DataValidade      time.Time          //Data de validade do cartão. Zero se não informada (a validação pública não retorna a validade)
+}
+
+// Identidade converte o IdUfu para o modelo único Identidade.
+func (i *IdUfu) Identidade() *Identidade {
+	identidade := &Identidade{
+		ID:                strconv.Itoa(i.ID),
+		Matricula:         i.Matricula,
+		Nome:              i.Nome,
+		NomePai:           i.NomePai,
+		NomeMae:           i.NomeMae,
+		Rg:                i.RG,
+		OrgaoEmissor:      i.OrgaoEmissor,
+		Cpf:               i.CPF,
+		Naturalidade:      i.Naturalidade,
+		Vinculo:           i.Vinculo,
+		Informacao:        i.Informacao,
+		CodigoBarra:       i.CodigoBarra,
+		Foto:              i.Foto,
+		Situacao:          SituacaoIdentidade(i.Situacao),
+		SituacaoDescricao: i.SituacaoDescricao,
+	}
+	if i.DataNascimento != 0 {
+		identidade.DataNascimento = time.UnixMilli(i.DataNascimento).In(fusoHorarioUfu)
+	}
+	return identidade
+}
+
+// Identidade converte a IdentidadeDigital para o modelo único Identidade.
+// Se o campo Situacao não for numérico, a situação fica como SituacaoDesconhecida.
+func (i *IdentidadeDigital) Identidade() *Identidade {
+	situacao, err := strconv.Atoi(strings.TrimSpace(i.Situacao))
+	if err != nil {
+		situacao = int(SituacaoDesconhecida)
+	}
+	return &Identidade{
+		ID:                i.ID,
+		Matricula:         i.Matricula,
+		Nome:              i.Nome,
+		NomePai:           i.NomePai,
+		NomeMae:           i.NomeMae,
+		Rg:                i.Rg,
+		OrgaoEmissor:      i.OrgaoEmissor,
+		Cpf:               i.Cpf,
+		Naturalidade:      i.Naturalidade,
+		Vinculo:           i.Vinculo,
+		Informacao:        i.Informacao,
+		CodigoBarra:       i.CodigoBarra,
+		Foto:              i.Foto,
+		Situacao:          SituacaoIdentidade(situacao),
+		SituacaoDescricao: i.SituacaoDescricao,
+		DataNascimento:    i.DataNascimento,
+		DataValidade:      i.DataValidade,
+	}
+}
+
+// IsValida informa se a identidade digital pode ser aceita no instante agora: a situação precisa ser ativa e,
+// se a DataValidade for informada, o cartão vale até o fim do dia da validade.
+// Quando a identidade não é válida, retorna false e um erro com o motivo (ErrIdentidadeInativa ou ErrIdentidadeVencida).
+func (i *Identidade) IsValida(agora time.Time) (bool, error) {
+	if !i.Situacao.Ativa() {
+		descricao := i.SituacaoDescricao
+		if descricao == "" {
+			descricao = i.Situacao.String()
+		}
+		return false, fmt.Errorf("%w: %s", ErrIdentidadeInativa, descricao)
+	}
+	if !i.DataValidade.IsZero() {
+		ano, mes, dia := i.DataValidade.Date()
+		vencimento := time.Date(ano, mes, dia+1, 0, 0, 0, 0, i.DataValidade.Location())
+		if !agora.Before(vencimento) {
+			return false, fmt.Errorf("%w: validade em %s", ErrIdentidadeVencida, i.DataValidade.Format("02/01/2006"))
+		}
+	}
+	return true, nil
+}
+
+// IsValida converte o IdUfu para Identidade e verifica se ele é válido no instante agora (veja Identidade.IsValida).
+func (i *IdUfu) IsValida(agora time.Time) (bool, error) {
+	return i.Identidade().IsValida(agora)
+}
+
+// IsValida converte a IdentidadeDigital para Identidade e verifica se ela é válida no instante agora (veja Identidade.IsValida).
+func (i *IdentidadeDigital) IsValida(agora time.Time) (bool, error) {
+	return i.Identidade().IsValida(agora)
+}
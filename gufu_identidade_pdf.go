@@ -0,0 +1,152 @@
+package gufu
+
+import (
+	"errors"
+	"fmt"
+	"image/color"
+	"io"
+	"strings"
+)
+
+// LayoutPDF é o formato do PDF gerado por IdentidadeDigital.GerarPDF.
+type LayoutPDF int
+
+const (
+	LayoutCartao LayoutPDF = iota //Uma página do tamanho de um cartão de crédito (85,6 x 54 mm)
+	LayoutA4                      //Uma folha A4 com o cartão em tamanho real e uma linha de corte
+)
+
+var (
+	larguraCartaoPDF = 85.6 * pontosPorMilimetro
+	alturaCartaoPDF  = 53.98 * pontosPorMilimetro
+	larguraA4PDF     = 210 * pontosPorMilimetro
+	alturaA4PDF      = 297 * pontosPorMilimetro
+
+	corUfuPDF       = color.RGBA{R: 0x00, G: 0x3c, B: 0x78, A: 0xff}
+	corTextoPDF     = color.RGBA{R: 0x22, G: 0x22, B: 0x22, A: 0xff}
+	corRotuloPDF    = color.RGBA{R: 0x77, G: 0x77, B: 0x77, A: 0xff}
+	corContornoPDF  = color.RGBA{R: 0xbb, G: 0xbb, B: 0xbb, A: 0xff}
+	corSemFotoPDF   = color.RGBA{R: 0xe6, G: 0xe6, B: 0xe6, A: 0xff}
+	corCabecalhoPDF = color.White
+)
+
+// GerarPDF gera uma versão imprimível da identidade digital, com nome, matrícula, curso, validade, foto e QR Code,
+// e escreve o PDF em w. Se a identidade não tiver foto, um espaço vazio é desenhado no lugar.
+func (i *IdentidadeDigital) GerarPDF(w io.Writer, layout LayoutPDF) error {
+	qr, err := i.QrCode(NivelCorrecaoM)
+	if err != nil {
+		return err
+	}
+
+	doc := &documentoPDF{titulo: "Identidade Digital UFU - " + i.Nome}
+	foto, proporcaoFoto := -1, 0.0
+	img, _, err := i.DecodificarFoto()
+	switch {
+	case err == nil:
+		if foto, err = doc.adicionarImagem(img); err != nil {
+			return err
+		}
+		proporcaoFoto = float64(img.Bounds().Dx()) / float64(img.Bounds().Dy())
+	case !errors.Is(err, ErrFotoVazia):
+		return err
+	}
+
+	switch layout {
+	case LayoutCartao:
+		pagina := doc.novaPagina(larguraCartaoPDF, alturaCartaoPDF)
+		i.desenharCartaoPDF(pagina, 0, 0, foto, proporcaoFoto, qr)
+	case LayoutA4:
+		pagina := doc.novaPagina(larguraA4PDF, alturaA4PDF)
+		margem := 20 * pontosPorMilimetro
+		pagina.texto(margem, margem, 14, true, corUfuPDF, "Identidade Digital - Universidade Federal de Uberlândia")
+
+		x := (larguraA4PDF - larguraCartaoPDF) / 2
+		y := margem + 15*pontosPorMilimetro
+		i.desenharCartaoPDF(pagina, x, y, foto, proporcaoFoto, qr)
+		//Linha de corte a 2 mm do cartão
+		corte := 2 * pontosPorMilimetro
+		pagina.contorno(x-corte, y-corte, larguraCartaoPDF+2*corte, alturaCartaoPDF+2*corte, 0.5, corRotuloPDF, true)
+
+		y += alturaCartaoPDF + 12*pontosPorMilimetro
+		for _, linha := range []string{
+			"Recorte na linha tracejada.",
+			"A autenticidade desta identidade pode ser conferida lendo o QR Code",
+			"ou acessando " + strings.TrimPrefix(validaUfuUrl, "https://") + ".",
+		} {
+			pagina.texto(margem, y, 10, false, corTextoPDF, linha)
+			y += 14
+		}
+	default:
+		return fmt.Errorf("layout de pdf inválido: %d", layout)
+	}
+	return doc.escrever(w)
+}
+
+// Desenha o cartão da identidade digital com o canto superior esquerdo em (x, y).
+// foto é o índice da imagem no documento (ou -1 se não houver foto) e proporcaoFoto é a largura dividida pela altura da foto.
+func (i *IdentidadeDigital) desenharCartaoPDF(p *paginaPDF, x, y float64, foto int, proporcaoFoto float64, qr *QrCode) {
+	const (
+		margem          = 8.0
+		alturaCabecalho = 24.0
+		larguraFoto     = 48.0
+		alturaFoto      = 64.0
+		ladoQr          = 62.0
+	)
+	p.retangulo(x, y, larguraCartaoPDF, alturaCartaoPDF, color.White)
+	p.contorno(x, y, larguraCartaoPDF, alturaCartaoPDF, 0.5, corContornoPDF, false)
+
+	//Cabeçalho
+	p.retangulo(x, y, larguraCartaoPDF, alturaCabecalho, corUfuPDF)
+	p.texto(x+margem, y+10, 6.5, true, corCabecalhoPDF, "UNIVERSIDADE FEDERAL DE UBERLÂNDIA")
+	subtitulo := "Identidade Digital"
+	if i.Vinculo != "" {
+		subtitulo += " - " + i.Vinculo
+	}
+	p.texto(x+margem, y+19, 6, false, corCabecalhoPDF, truncarTextoPDF(subtitulo, larguraCartaoPDF-2*margem, 6, false, false))
+
+	//Foto, centralizada no espaço e mantendo a proporção
+	topo := y + alturaCabecalho + 6
+	if foto >= 0 {
+		largura, altura := larguraFoto, alturaFoto
+		if proporcaoFoto > larguraFoto/alturaFoto {
+			altura = larguraFoto / proporcaoFoto
+		} else {
+			largura = alturaFoto * proporcaoFoto
+		}
+		p.imagem(foto, x+margem+(larguraFoto-largura)/2, topo+(alturaFoto-altura)/2, largura, altura)
+	} else {
+		p.retangulo(x+margem, topo, larguraFoto, alturaFoto, corSemFotoPDF)
+		p.texto(x+margem+(larguraFoto-larguraTextoPDF("SEM FOTO", 5, true))/2, topo+alturaFoto/2, 5, true, corRotuloPDF, "SEM FOTO")
+	}
+
+	//QR Code
+	p.qrCode(qr, x+larguraCartaoPDF-margem-ladoQr, topo, ladoQr)
+
+	//Dados, entre a foto e o QR Code
+	colunaX := x + margem + larguraFoto + 6
+	larguraColuna := larguraCartaoPDF - 2*margem - larguraFoto - ladoQr - 12
+	linhaY := topo + 4
+	campo := func(rotulo, valor string, tamanho float64, negrito bool, maxLinhas int) {
+		p.texto(colunaX, linhaY, 4.5, false, corRotuloPDF, rotulo)
+		linhaY += tamanho + 1
+		if valor == "" {
+			valor = "-"
+		}
+		for _, l := range quebrarTextoPDF(valor, larguraColuna, tamanho, negrito, maxLinhas) {
+			p.texto(colunaX, linhaY, tamanho, negrito, corTextoPDF, l)
+			linhaY += tamanho + 1
+		}
+		linhaY += 4
+	}
+	campo("NOME", i.Nome, 6.5, true, 2)
+	campo("MATRÍCULA", i.Matricula, 6.5, false, 1)
+	campo("CURSO", i.Informacao, 5.5, false, 3)
+	validade := "-"
+	if !i.DataValidade.IsZero() {
+		validade = i.DataValidade.Format("02/01/2006")
+	}
+	campo("VALIDADE", validade, 6.5, false, 1)
+
+	rodape := "Valide em " + strings.TrimPrefix(validaUfuUrl, "https://")
+	p.texto(x+margem, y+alturaCartaoPDF-6, 4.5, false, corRotuloPDF, truncarTextoPDF(rodape, larguraCartaoPDF-2*margem, 4.5, false, false))
+}
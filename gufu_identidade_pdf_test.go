@@ -0,0 +1,122 @@
+package gufu
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+var identidadeExemplo = IdentidadeDigital{
+	Nome:         "Fulano de Tal",
+	Matricula:    "12345SIS001",
+	Informacao:   "Graduação em Sistemas de Informação: Bacharelado - Noturno",
+	Vinculo:      "Aluno",
+	CodigoBarra:  "12352998224725",
+	DataValidade: time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC),
+}
+
+// Confere a estrutura do PDF e retorna o conteúdo descomprimido de todas as páginas
+func conteudoPDF(t *testing.T, pdf []byte) string {
+	t.Helper()
+	if !bytes.HasPrefix(pdf, []byte("%PDF-")) || !bytes.HasSuffix(pdf, []byte("%%EOF\n")) {
+		t.Fatal("cabeçalho ou final do pdf inválido")
+	}
+
+	//Cada entrada da tabela xref deve apontar para o início do objeto correspondente
+	inicio := bytes.LastIndex(pdf, []byte("startxref\n"))
+	posXref, err := strconv.Atoi(strings.Fields(string(pdf[inicio+len("startxref\n"):]))[0])
+	if err != nil || !bytes.HasPrefix(pdf[posXref:], []byte("xref\n")) {
+		t.Fatalf("startxref inválido: %v", err)
+	}
+	linhas := strings.Split(string(pdf[posXref:]), "\n")
+	var total int
+	fmt.Sscanf(linhas[1], "0 %d", &total)
+	for i := 1; i < total; i++ {
+		pos, _ := strconv.Atoi(linhas[2+i][:10])
+		if esperado := fmt.Sprintf("%d 0 obj\n", i); !bytes.HasPrefix(pdf[pos:], []byte(esperado)) {
+			t.Fatalf("xref do objeto %d aponta para a posição errada", i)
+		}
+	}
+
+	var conteudo strings.Builder
+	fluxos := regexp.MustCompile(`(?s)/FlateDecode >>\nstream\n(.*?)\nendstream`)
+	for _, m := range fluxos.FindAllSubmatch(pdf, -1) {
+		r, err := zlib.NewReader(bytes.NewReader(m[1]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		dados, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		conteudo.Write(dados)
+	}
+	return conteudo.String()
+}
+
+func TestGerarPDF(t *testing.T) {
+	for _, layout := range []LayoutPDF{LayoutCartao, LayoutA4} {
+		var buf bytes.Buffer
+		if err := identidadeExemplo.GerarPDF(&buf, layout); err != nil {
+			t.Fatal(err)
+		}
+		conteudo := conteudoPDF(t, buf.Bytes())
+		for _, esperado := range []string{"(Fulano de Tal) Tj", "(12345SIS001) Tj", "(31/12/2026) Tj", "(SEM FOTO) Tj"} {
+			if !strings.Contains(conteudo, esperado) {
+				t.Errorf("layout %d: %q não encontrado no conteúdo", layout, esperado)
+			}
+		}
+		if layout == LayoutA4 && !strings.Contains(buf.String(), "/MediaBox [0 0 595.28 841.89]") {
+			t.Errorf("a página A4 deveria ter 210 x 297 mm")
+		}
+	}
+
+	if err := identidadeExemplo.GerarPDF(io.Discard, LayoutPDF(9)); err == nil {
+		t.Fatal("layout inválido deveria retornar erro")
+	}
+	if err := (&IdentidadeDigital{Nome: "Sem QR Code"}).GerarPDF(io.Discard, LayoutCartao); err == nil {
+		t.Fatal("identidade sem código de barras deveria retornar erro")
+	}
+}
+
+func TestGerarPDFComFoto(t *testing.T) {
+	id := identidadeExemplo
+	id.Foto = fotoBase64(t, 90, 120, func(b *bytes.Buffer, img image.Image) error { return jpeg.Encode(b, img, nil) })
+	var buf bytes.Buffer
+	if err := id.GerarPDF(&buf, LayoutCartao); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "/Subtype /Image /Width 90 /Height 120") {
+		t.Fatal("a foto deveria ser incluída no pdf")
+	}
+	if conteudo := conteudoPDF(t, buf.Bytes()); strings.Contains(conteudo, "SEM FOTO") || !strings.Contains(conteudo, "/Im0 Do") {
+		t.Fatal("a foto deveria ser desenhada no lugar do espaço vazio")
+	}
+
+	id.Foto = "não é uma foto"
+	if err := id.GerarPDF(io.Discard, LayoutCartao); err == nil {
+		t.Fatal("foto inválida deveria retornar erro")
+	}
+}
+
+func TestLarguraTextoPDF(t *testing.T) {
+	for acentuado, semAcento := range map[string]string{
+		"Uberlândia, Física": "Uberlandia, Fisica",
+		"íìîï ÍÌÎÏ":          "iiii IIII",
+		"Conceição nº 1":     "Conceicao no 1",
+	} {
+		for _, negrito := range []bool{false, true} {
+			if a, b := larguraTextoPDF(acentuado, 10, negrito), larguraTextoPDF(semAcento, 10, negrito); a != b {
+				t.Errorf("largura de %q = %v, esperava a de %q (%v)", acentuado, a, semAcento, b)
+			}
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package gufu
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIdentidadeDeIdUfu(t *testing.T) {
+	id := IdUfu{
+		ID:             1234,
+		Nome:           "Fulano de Tal",
+		CPF:            "52998224725",
+		RG:             "MG1234567",
+		DataNascimento: 946695600000, //01/01/2000 00:00 em Uberlândia
+		Situacao:       3,
+		CodigoBarra:    "12352998224725",
+	}
+	identidade := id.Identidade()
+	if identidade.ID != "1234" || identidade.Cpf != id.CPF || identidade.Rg != id.RG || identidade.Situacao != SituacaoAtiva {
+		t.Fatalf("conversão inesperada: %+v", identidade)
+	}
+	if d := identidade.DataNascimento; d.Year() != 2000 || d.Month() != time.January || d.Day() != 1 || d.Hour() != 0 {
+		t.Fatalf("data de nascimento inesperada: %v", d)
+	}
+	if !identidade.DataValidade.IsZero() {
+		t.Fatal("a validação pública não retorna a validade")
+	}
+	if !(&IdUfu{}).Identidade().DataNascimento.IsZero() {
+		t.Fatal("data de nascimento ausente deveria ficar zerada")
+	}
+}
+
+func TestIdentidadeDeIdentidadeDigital(t *testing.T) {
+	id := identidadeExemplo
+	id.Situacao = " 3 "
+	id.ID = "1234"
+	identidade := id.Identidade()
+	if identidade.ID != "1234" || identidade.Nome != id.Nome || identidade.Situacao != SituacaoAtiva || !identidade.DataValidade.Equal(id.DataValidade) {
+		t.Fatalf("conversão inesperada: %+v", identidade)
+	}
+	id.Situacao = "Ativa"
+	if s := id.Identidade().Situacao; s != SituacaoDesconhecida {
+		t.Fatalf("situação não numérica deveria ser desconhecida, obtido %d", s)
+	}
+}
+
+func TestSituacaoIdentidade(t *testing.T) {
+	casos := map[SituacaoIdentidade]string{SituacaoAtiva: "Ativa", SituacaoDesconhecida: "Desconhecida", 7: "Situação 7"}
+	for s, esperado := range casos {
+		if s.String() != esperado {
+			t.Errorf("%d: esperado %q, obtido %q", int(s), esperado, s.String())
+		}
+	}
+
+	//Códigos sem constante não são ativos e são descritos pelo número quando a API não envia a descrição
+	for _, s := range []SituacaoIdentidade{1, 2, 4, 7, -1} {
+		if s.Conhecida() || s.Ativa() {
+			t.Errorf("%d: não deveria ser conhecida nem ativa", int(s))
+		}
+		_, err := (&Identidade{Situacao: s}).IsValida(time.Now())
+		if !errors.Is(err, ErrIdentidadeInativa) || !strings.HasSuffix(err.Error(), s.String()) {
+			t.Errorf("%d: esperado ErrIdentidadeInativa com %q, obtido %v", int(s), s.String(), err)
+		}
+	}
+	if !SituacaoAtiva.Conhecida() || !SituacaoDesconhecida.Conhecida() {
+		t.Fatal("as constantes deveriam ser conhecidas")
+	}
+}
+
+func TestIsValida(t *testing.T) {
+	validade := time.Date(2026, 12, 31, 0, 0, 0, 0, fusoHorarioUfu)
+	casos := []struct {
+		identidade Identidade
+		agora      time.Time
+		erro       error
+	}{
+		{Identidade{Situacao: SituacaoAtiva, DataValidade: validade}, validade.Add(23 * time.Hour), nil},
+		{Identidade{Situacao: SituacaoAtiva, DataValidade: validade}, validade.Add(24 * time.Hour), ErrIdentidadeVencida},
+		{Identidade{Situacao: SituacaoAtiva}, validade.AddDate(10, 0, 0), nil},
+		{Identidade{Situacao: 1, SituacaoDescricao: "Cancelada", DataValidade: validade}, validade, ErrIdentidadeInativa},
+		{Identidade{}, validade, ErrIdentidadeInativa},
+	}
+	for i, c := range casos {
+		valida, err := c.identidade.IsValida(c.agora)
+		if !errors.Is(err, c.erro) || valida != (c.erro == nil) {
+			t.Errorf("caso %d: esperado %v, obtido %v %v", i, c.erro, valida, err)
+		}
+	}
+	if _, err := (&IdUfu{Situacao: 2, SituacaoDescricao: "Bloqueada"}).IsValida(validade); err == nil || !strings.Contains(err.Error(), "Bloqueada") {
+		t.Fatalf("o motivo deveria incluir a descrição da situação: %v", err)
+	}
+}
@@ -0,0 +1,131 @@
+package gufu
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"unicode/utf8"
+)
+
+var ErrTextoInvalido = errors.New("envelope inválido: o texto descriptografado não é um JSON (a senha ou as chaves estão erradas?)") //O padding confere, mas o texto descriptografado não é um JSON em UTF-8
+
+// Formato de um corpo trocado com a API do aplicativo, identificado por Inspecionar
+type FormatoCorpo string
+
+const (
+	FormatoVazio        FormatoCorpo = "vazio"        //Corpo vazio
+	FormatoRequisicao   FormatoCorpo = "requisicao"   //Requisição criptografada, no formato {"requestParams":"..."}
+	FormatoResposta     FormatoCorpo = "resposta"     //Resposta criptografada (texto criptografado, MarcadorResposta e senha)
+	FormatoErroMobile   FormatoCorpo = "erro"         //JSON de erro da API (ErrorMobile), enviado sem criptografia
+	FormatoJSON         FormatoCorpo = "json"         //JSON sem criptografia
+	FormatoDesconhecido FormatoCorpo = "desconhecido" //Nenhum dos formatos acima
+)
+
+// Resultado de Inspecionar
+type Inspecao struct {
+	Formato    FormatoCorpo `json:"formato"`
+	Marcador   string       `json:"marcador,omitempty"`   //Marcador encontrado no envelope
+	Cifrado    string       `json:"cifrado,omitempty"`    //Texto criptografado em base64, antes do marcador
+	Senha      string       `json:"senha,omitempty"`      //Senha do envelope, depois do marcador
+	Texto      string       `json:"texto,omitempty"`      //JSON descriptografado (ou o próprio corpo, se ele não estiver criptografado)
+	ErroMobile *ErrorMobile `json:"erroMobile,omitempty"` //Erro da API, se o formato for FormatoErroMobile
+	Err        error        `json:"-"`                    //Erro ao abrir o envelope (ErrEnvelopeSemMarcador, ErrCifradoInvalido, ErrPaddingInvalido, ErrTextoInvalido...)
+	Erro       string       `json:"erro,omitempty"`       //Mensagem de Err
+}
+
+// Inspecionar identifica o formato de um corpo trocado com a API do aplicativo usando o CodecPadrao.
+// Veja Codec.Inspecionar.
+func Inspecionar(corpo string) *Inspecao {
+	return CodecPadrao.Inspecionar(corpo)
+}
+
+// Inspecionar identifica se o corpo é uma requisição criptografada, uma resposta criptografada, um ErrorMobile,
+// um JSON sem criptografia ou algo desconhecido, separa o texto criptografado e a senha dos envelopes
+// e os descriptografa quando possível. Ao contrário de Decode e DecodeRequest, um envelope que não pode ser aberto
+// não impede a inspeção: as partes encontradas são retornadas junto com o erro em Err.
+func (c *Codec) Inspecionar(corpo string) *Inspecao {
+	corpo = strings.TrimSpace(corpo)
+	if corpo == "" {
+		return &Inspecao{Formato: FormatoVazio}
+	}
+
+	var objeto map[string]json.RawMessage
+	if json.Unmarshal([]byte(corpo), &objeto) == nil {
+		if bruto, ok := objeto["requestParams"]; ok {
+			var envelope string
+			if json.Unmarshal(bruto, &envelope) == nil {
+				return c.inspecionarEnvelope(FormatoRequisicao, envelope, c.MarcadorRequisicao)
+			}
+		}
+		if erro := erroMobileDe(objeto); erro != nil {
+			return &Inspecao{Formato: FormatoErroMobile, Texto: corpo, ErroMobile: erro}
+		}
+	}
+	if json.Valid([]byte(corpo)) {
+		return &Inspecao{Formato: FormatoJSON, Texto: corpo}
+	}
+
+	if c.MarcadorResposta != "" && strings.Contains(corpo, c.MarcadorResposta) {
+		return c.inspecionarEnvelope(FormatoResposta, corpo, c.MarcadorResposta)
+	}
+	if c.MarcadorRequisicao != "" && strings.Contains(corpo, c.MarcadorRequisicao) {
+		//O conteúdo de requestParams sem o JSON em volta
+		return c.inspecionarEnvelope(FormatoRequisicao, corpo, c.MarcadorRequisicao)
+	}
+	inspecao := &Inspecao{Formato: FormatoDesconhecido}
+	if pareceBase64(corpo) {
+		//Provavelmente um envelope de outro Codec ou com o marcador cortado
+		inspecao.Err, inspecao.Erro = ErrEnvelopeSemMarcador, ErrEnvelopeSemMarcador.Error()
+	}
+	return inspecao
+}
+
+func (c *Codec) inspecionarEnvelope(formato FormatoCorpo, envelope, marcador string) *Inspecao {
+	inspecao := &Inspecao{Formato: formato, Marcador: marcador}
+	cifrado, senha, err := separarEnvelope(envelope, marcador)
+	if err == nil {
+		inspecao.Cifrado, inspecao.Senha = cifrado, senha
+		inspecao.Texto, err = c.descriptografarTexto(cifrado, senha)
+		switch {
+		case err != nil:
+		case inspecao.Texto == "":
+			err = ErrEnvelopeVazio
+		case !utf8.ValidString(inspecao.Texto) || !json.Valid([]byte(inspecao.Texto)):
+			//Com uma senha errada, o padding confere por acaso em cerca de 1 a cada 256 envelopes
+			err = ErrTextoInvalido
+		}
+	}
+	if err != nil {
+		inspecao.Texto = ""
+		inspecao.Err, inspecao.Erro = err, err.Error()
+	}
+	return inspecao
+}
+
+// Retorna o ErrorMobile se o objeto tiver o formato dos erros da API (status numérico e error ou message)
+func erroMobileDe(objeto map[string]json.RawMessage) *ErrorMobile {
+	var status int
+	if json.Unmarshal(objeto["status"], &status) != nil {
+		return nil
+	}
+	_, temErro := objeto["error"]
+	_, temMensagem := objeto["message"]
+	if !temErro && !temMensagem {
+		return nil
+	}
+	var erro ErrorMobile
+	dados, _ := json.Marshal(objeto)
+	if json.Unmarshal(dados, &erro) != nil {
+		return nil
+	}
+	return &erro
+}
+
+func pareceBase64(texto string) bool {
+	for _, r := range texto {
+		if !(r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '+' || r == '/' || r == '=') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,82 @@
+package gufu
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestInspecionar(t *testing.T) {
+	//Senhas fixas, para que a senha errada abaixo nunca seja igual à certa
+	codec := *CodecPadrao
+	codec.Aleatorio = &bytesSequenciais{}
+	texto := `{"login":"fulano"}`
+	requisicao, err := codec.Encode(texto)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resposta, err := codec.EncodeResponse(texto)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cifrado, senha, _ := strings.Cut(resposta, codec.MarcadorResposta)
+	senhaErrada := "x" + senha[1:]
+	if senhaErrada == senha {
+		t.Fatalf("a senha %q já começa com x", senha)
+	}
+	//Uma senha errada com a qual o padding confere por acaso, produzindo bytes aleatórios
+	senhaComPadding := ""
+	for i := 0; senhaComPadding == "" && i < 100000; i++ {
+		candidata := fmt.Sprintf("%s%05d", senha[:len(senha)-5], i)
+		if _, err := codec.descriptografarTexto(cifrado, candidata); err == nil && candidata != senha {
+			senhaComPadding = candidata
+		}
+	}
+	if senhaComPadding == "" {
+		t.Fatal("nenhuma senha errada com padding válido foi encontrada")
+	}
+
+	testes := []struct {
+		nome    string
+		corpo   string
+		formato FormatoCorpo
+		texto   string
+		err     error
+	}{
+		{"vazio", "  ", FormatoVazio, "", nil},
+		{"requisicao", requisicao, FormatoRequisicao, texto, nil},
+		{"resposta", "\n" + resposta + "\n", FormatoResposta, texto, nil},
+		{"json", `{"a":1}`, FormatoJSON, `{"a":1}`, nil},
+		{"lista", `[1,2]`, FormatoJSON, `[1,2]`, nil},
+		{"erro", `{"timestamp":1,"status":401,"error":"Unauthorized","message":"Bad credentials","path":"/x"}`, FormatoErroMobile, "", nil},
+		{"sem senha", cifrado + codec.MarcadorResposta, FormatoResposta, "", ErrEnvelopeSemSenha},
+		{"senha errada", cifrado + codec.MarcadorResposta + senhaErrada, FormatoResposta, "", ErrPaddingInvalido},
+		{"senha errada com padding válido", cifrado + codec.MarcadorResposta + senhaComPadding, FormatoResposta, "", ErrTextoInvalido},
+		{"cifrado cortado", cifrado[4:] + codec.MarcadorResposta + senha, FormatoResposta, "", ErrCifradoInvalido},
+		{"requestParams sem marcador", `{"requestParams":"abc"}`, FormatoRequisicao, "", ErrEnvelopeSemMarcador},
+		{"base64 sem marcador", cifrado, FormatoDesconhecido, "", ErrEnvelopeSemMarcador},
+		{"texto", "<html>", FormatoDesconhecido, "", nil},
+	}
+	for _, teste := range testes {
+		inspecao := codec.Inspecionar(teste.corpo)
+		if inspecao.Formato != teste.formato {
+			t.Errorf("%s: formato = %q, esperava %q", teste.nome, inspecao.Formato, teste.formato)
+		}
+		if teste.formato != FormatoErroMobile && inspecao.Texto != teste.texto {
+			t.Errorf("%s: texto = %q, esperava %q", teste.nome, inspecao.Texto, teste.texto)
+		}
+		if !errors.Is(inspecao.Err, teste.err) || (inspecao.Err == nil) != (inspecao.Erro == "") {
+			t.Errorf("%s: err = %v (%q), esperava %v", teste.nome, inspecao.Err, inspecao.Erro, teste.err)
+		}
+	}
+
+	inspecao := Inspecionar(resposta)
+	if inspecao.Cifrado != cifrado || inspecao.Senha != senha || inspecao.Marcador != CodecPadrao.MarcadorResposta || inspecao.Texto != texto {
+		t.Fatalf("partes do envelope inesperadas: %+v", inspecao)
+	}
+	inspecao = Inspecionar(`{"status":500,"message":"falhou"}`)
+	if inspecao.ErroMobile == nil || inspecao.ErroMobile.Status != 500 || inspecao.ErroMobile.Message != "falhou" {
+		t.Fatalf("ErroMobile inesperado: %+v", inspecao.ErroMobile)
+	}
+}
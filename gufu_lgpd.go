@@ -0,0 +1,340 @@
+package gufu
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Funções para evitar que dados pessoais (LGPD) vazem em logs. Os tipos com dados pessoais implementam
+// String, GoString e slog.LogValuer mostrando apenas os campos mascarados, então "%v", "%+v", "%#v" e
+// slog não expõem CPF, RG, nomes dos pais, data de nascimento, fotos ou tokens.
+// Para acessar os dados, use os campos diretamente ou Identidade.Projetar com a finalidade adequada.
+
+const omitido = "[omitido]" //Valor exibido no lugar de tokens e dados que não devem aparecer nem mascarados
+
+// MascararCpf mantém apenas os 6 dígitos do meio do CPF (Ex: "529.982.247-25" vira "***.982.247-**").
+// Se o valor não tiver 11 dígitos, retorna "***" (ou vazio, se o valor for vazio).
+func MascararCpf(cpf string) string {
+	digitos := apenasDigitos(cpf)
+	if len(digitos) != 11 {
+		return mascararTudo(cpf)
+	}
+	return "***." + digitos[3:6] + "." + digitos[6:9] + "-**"
+}
+
+// MascararRg mantém apenas os 2 últimos caracteres do RG (Ex: "MG1234567" vira "*******67").
+func MascararRg(rg string) string {
+	rg = strings.TrimSpace(rg)
+	runas := []rune(rg)
+	if len(runas) <= 2 {
+		return mascararTudo(rg)
+	}
+	return strings.Repeat("*", len(runas)-2) + string(runas[len(runas)-2:])
+}
+
+// MascararNome mantém o primeiro nome e a inicial do último sobrenome (Ex: "Fulano de Tal" vira "Fulano T.").
+func MascararNome(nome string) string {
+	partes := strings.Fields(nome)
+	switch len(partes) {
+	case 0:
+		return ""
+	case 1:
+		return partes[0]
+	}
+	ultimo := []rune(partes[len(partes)-1])
+	return partes[0] + " " + string(unicode.ToUpper(ultimo[0])) + "."
+}
+
+// MascararEmail mantém a primeira letra do usuário e o domínio (Ex: "fulano@ufu.br" vira "f***@ufu.br").
+func MascararEmail(email string) string {
+	arroba := strings.LastIndexByte(email, '@')
+	if arroba <= 0 {
+		return mascararTudo(email)
+	}
+	primeira := []rune(email[:arroba])[0]
+	return string(primeira) + "***" + email[arroba:]
+}
+
+// MascararData mantém apenas o ano da data (Ex: 01/02/2000 vira "**/**/2000"). Datas zeradas retornam vazio.
+func MascararData(data time.Time) string {
+	if data.IsZero() {
+		return ""
+	}
+	return "**/**/" + strconv.Itoa(data.Year())
+}
+
+// MascararIdUfu mantém o prefixo e mascara o CPF contido no id ufu (Ex: "12352998224725" vira "123***982247**").
+// Também aceita a URL do valida-ufu ou o conteúdo do QR Code. Entradas inválidas retornam "***".
+func MascararIdUfu(id string) string {
+	ref, err := InterpretarIdDigital(id)
+	if err != nil {
+		return mascararTudo(id)
+	}
+	return ref.Prefixo + strings.NewReplacer(".", "", "-", "").Replace(MascararCpf(ref.Cpf))
+}
+
+func mascararTudo(valor string) string {
+	if strings.TrimSpace(valor) == "" {
+		return ""
+	}
+	return "***"
+}
+
+func apenasDigitos(valor string) string {
+	return strings.Map(func(r rune) rune {
+		if r < '0' || r > '9' {
+			return -1
+		}
+		return r
+	}, valor)
+}
+
+// Descreve uma foto em base64 sem o conteúdo (Ex: "[foto, 12345 bytes]").
+func descreverFoto(foto string) string {
+	if foto == "" {
+		return ""
+	}
+	return fmt.Sprintf("[foto, %d bytes]", len(foto))
+}
+
+// Omite um valor secreto, indicando apenas se ele existe.
+func omitir(valor string) string {
+	if valor == "" {
+		return ""
+	}
+	return omitido
+}
+
+// Formata os atributos mascarados no estilo "Tipo{chave=valor ...}", ignorando os valores vazios.
+func formatarMascarado(tipo string, atributos []slog.Attr) string {
+	var b strings.Builder
+	b.WriteString(tipo)
+	b.WriteByte('{')
+	primeiro := true
+	for _, a := range atributos {
+		valor := a.Value.String()
+		if valor == "" {
+			continue
+		}
+		if !primeiro {
+			b.WriteByte(' ')
+		}
+		primeiro = false
+		b.WriteString(a.Key)
+		b.WriteByte('=')
+		if strings.ContainsAny(valor, " =\"") {
+			valor = strconv.Quote(valor)
+		}
+		b.WriteString(valor)
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+func (i Identidade) atributosMascarados() []slog.Attr {
+	validade := ""
+	if !i.DataValidade.IsZero() {
+		validade = i.DataValidade.Format("02/01/2006")
+	}
+	return []slog.Attr{
+		slog.String("id", i.ID),
+		slog.String("nome", MascararNome(i.Nome)),
+		slog.String("matricula", i.Matricula),
+		slog.String("cpf", MascararCpf(i.Cpf)),
+		slog.String("rg", MascararRg(i.Rg)),
+		slog.String("nomePai", mascararTudo(i.NomePai)),
+		slog.String("nomeMae", mascararTudo(i.NomeMae)),
+		slog.String("dataNascimento", MascararData(i.DataNascimento)),
+		slog.String("vinculo", i.Vinculo),
+		slog.String("informacao", i.Informacao),
+		slog.String("codigoBarra", MascararIdUfu(i.CodigoBarra)),
+		slog.String("foto", descreverFoto(i.Foto)),
+		slog.String("situacao", i.Situacao.String()),
+		slog.String("dataValidade", validade),
+	}
+}
+
+// String retorna a identidade com os dados pessoais mascarados.
+func (i Identidade) String() string { return formatarMascarado("Identidade", i.atributosMascarados()) }
+
+// GoString retorna a identidade com os dados pessoais mascarados, para que "%#v" também não os exponha.
+func (i Identidade) GoString() string { return i.String() }
+
+// LogValue implementa slog.LogValuer, registrando apenas os dados mascarados.
+func (i Identidade) LogValue() slog.Value { return slog.GroupValue(i.atributosMascarados()...) }
+
+// String retorna o IdUfu com os dados pessoais mascarados.
+func (i IdUfu) String() string {
+	return formatarMascarado("IdUfu", i.Identidade().atributosMascarados())
+}
+
+// GoString retorna o IdUfu com os dados pessoais mascarados, para que "%#v" também não os exponha.
+func (i IdUfu) GoString() string { return i.String() }
+
+// LogValue implementa slog.LogValuer, registrando apenas os dados mascarados.
+func (i IdUfu) LogValue() slog.Value { return slog.GroupValue(i.Identidade().atributosMascarados()...) }
+
+// String retorna a IdentidadeDigital com os dados pessoais mascarados.
+func (i IdentidadeDigital) String() string {
+	return formatarMascarado("IdentidadeDigital", i.Identidade().atributosMascarados())
+}
+
+// GoString retorna a IdentidadeDigital com os dados pessoais mascarados, para que "%#v" também não os exponha.
+func (i IdentidadeDigital) GoString() string { return i.String() }
+
+// LogValue implementa slog.LogValuer, registrando apenas os dados mascarados.
+func (i IdentidadeDigital) LogValue() slog.Value {
+	return slog.GroupValue(i.Identidade().atributosMascarados()...)
+}
+
+func (r IdDigitalRef) atributosMascarados() []slog.Attr {
+	return []slog.Attr{
+		slog.String("id", MascararIdUfu(r.Id)),
+		slog.String("prefixo", r.Prefixo),
+		slog.String("cpf", MascararCpf(r.Cpf)),
+	}
+}
+
+// String retorna o id mascarado (Ex: "123***982247**"). Para o id completo, use IdCompleto.
+func (r IdDigitalRef) String() string { return MascararIdUfu(r.Id) }
+
+// GoString retorna a referência com o CPF mascarado, para que "%#v" também não o exponha.
+func (r IdDigitalRef) GoString() string {
+	return formatarMascarado("IdDigitalRef", r.atributosMascarados())
+}
+
+// LogValue implementa slog.LogValuer, registrando apenas o id e o CPF mascarados.
+func (r IdDigitalRef) LogValue() slog.Value { return slog.GroupValue(r.atributosMascarados()...) }
+
+func (r ResultadoLote) atributosMascarados() []slog.Attr {
+	atributos := []slog.Attr{
+		slog.String("entrada", MascararIdUfu(r.Entrada)),
+		slog.String("id", MascararIdUfu(r.Id)),
+		slog.Bool("valida", r.Valida),
+	}
+	if r.IdUfu != nil {
+		atributos = append(atributos, slog.Any("idUfu", *r.IdUfu))
+	}
+	if r.Err != nil {
+		atributos = append(atributos, slog.String("erro", r.Err.Error()))
+	}
+	return atributos
+}
+
+// String retorna o resultado com a entrada, o id e a identidade mascarados.
+func (r ResultadoLote) String() string {
+	return formatarMascarado("ResultadoLote", r.atributosMascarados())
+}
+
+// GoString retorna o resultado mascarado, para que "%#v" também não exponha o CPF.
+func (r ResultadoLote) GoString() string { return r.String() }
+
+// LogValue implementa slog.LogValuer, registrando apenas os dados mascarados.
+func (r ResultadoLote) LogValue() slog.Value { return slog.GroupValue(r.atributosMascarados()...) }
+
+func (d DadosSSO) atributosMascarados() []slog.Attr {
+	return []slog.Attr{
+		slog.String("nome", MascararNome(d.Nome)),
+		slog.String("cpf", MascararCpf(d.Cpf)),
+		slog.String("email", MascararEmail(d.Email)),
+		slog.Int("idPessoa", d.IDPessoa),
+		slog.String("chave", omitir(d.Chave)),
+		slog.String("accessTokenId", omitir(d.AccessTokenID)),
+		slog.Any("roles", d.Roles),
+		slog.Int("cookies", len(d.Cookies)),
+	}
+}
+
+// String retorna os dados do SSO com os dados pessoais mascarados e sem os tokens.
+func (d DadosSSO) String() string { return formatarMascarado("DadosSSO", d.atributosMascarados()) }
+
+// GoString retorna os dados do SSO mascarados, para que "%#v" também não os exponha.
+func (d DadosSSO) GoString() string { return d.String() }
+
+// LogValue implementa slog.LogValuer, registrando apenas os dados mascarados e sem os tokens.
+func (d DadosSSO) LogValue() slog.Value { return slog.GroupValue(d.atributosMascarados()...) }
+
+func (d DadosLoginMobile) atributosMascarados() []slog.Attr {
+	email := ""
+	if d.Email != nil {
+		email = MascararEmail(*d.Email)
+	}
+	return []slog.Attr{
+		slog.String("nome", MascararNome(d.Nome)),
+		slog.String("email", email),
+		slog.String("token", omitir(d.Token)),
+		slog.Int("perfilAtivo", d.PerfilAtivo.IDPerfil),
+		slog.Int("perfis", len(d.Perfis)),
+		slog.String("avatar", descreverFoto(d.Avatar)),
+	}
+}
+
+// String retorna os dados do login com os dados pessoais mascarados e sem o token.
+func (d DadosLoginMobile) String() string {
+	return formatarMascarado("DadosLoginMobile", d.atributosMascarados())
+}
+
+// GoString retorna os dados do login mascarados, para que "%#v" também não os exponha.
+func (d DadosLoginMobile) GoString() string { return d.String() }
+
+// LogValue implementa slog.LogValuer, registrando apenas os dados mascarados e sem o token.
+func (d DadosLoginMobile) LogValue() slog.Value { return slog.GroupValue(d.atributosMascarados()...) }
+
+// Finalidade é o caso de uso para o qual os dados de uma identidade serão usados. Veja Identidade.Projetar.
+type Finalidade int
+
+const (
+	FinalidadeControleAcesso Finalidade = iota //Liberar a entrada em um local: nome, matrícula, vínculo, foto, situação e validade
+	FinalidadeValidacao                        //Confirmar que a identidade existe e está válida, sem dados pessoais além do nome e da matrícula
+	FinalidadeEstatistica                      //Relatórios agregados: apenas vínculo, curso e situação
+	FinalidadeCompleta                         //Todos os campos. Use apenas quando o tratamento de todos os dados for justificado
+)
+
+// CamposPorFinalidade lista os campos (com os nomes usados em Projecao) liberados para cada finalidade.
+// Pode ser alterado para atender necessidades específicas.
+var CamposPorFinalidade = map[Finalidade][]string{
+	FinalidadeControleAcesso: {"nome", "matricula", "vinculo", "foto", "situacao", "dataValidade"},
+	FinalidadeValidacao:      {"nome", "matricula", "situacao", "dataValidade"},
+	FinalidadeEstatistica:    {"vinculo", "informacao", "situacao"},
+	FinalidadeCompleta: {"id", "matricula", "nome", "nomePai", "nomeMae", "rg", "orgaoEmissor", "cpf", "naturalidade",
+		"vinculo", "informacao", "codigoBarra", "foto", "situacao", "situacaoDescricao", "dataNascimento", "dataValidade"},
+}
+
+// Projecao contém apenas os campos de uma identidade liberados para uma finalidade, com os mesmos nomes usados no JSON.
+// As datas são time.Time e a situação é SituacaoIdentidade.
+type Projecao map[string]any
+
+// Projetar retorna apenas os campos da identidade liberados para a finalidade, de acordo com CamposPorFinalidade.
+// Finalidades desconhecidas retornam uma projeção vazia.
+func (i *Identidade) Projetar(finalidade Finalidade) Projecao {
+	todos := map[string]any{
+		"id":                i.ID,
+		"matricula":         i.Matricula,
+		"nome":              i.Nome,
+		"nomePai":           i.NomePai,
+		"nomeMae":           i.NomeMae,
+		"rg":                i.Rg,
+		"orgaoEmissor":      i.OrgaoEmissor,
+		"cpf":               i.Cpf,
+		"naturalidade":      i.Naturalidade,
+		"vinculo":           i.Vinculo,
+		"informacao":        i.Informacao,
+		"codigoBarra":       i.CodigoBarra,
+		"foto":              i.Foto,
+		"situacao":          i.Situacao,
+		"situacaoDescricao": i.SituacaoDescricao,
+		"dataNascimento":    i.DataNascimento,
+		"dataValidade":      i.DataValidade,
+	}
+	projecao := Projecao{}
+	for _, campo := range CamposPorFinalidade[finalidade] {
+		if valor, ok := todos[campo]; ok {
+			projecao[campo] = valor
+		}
+	}
+	return projecao
+}
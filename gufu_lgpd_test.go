@@ -0,0 +1,110 @@
+package gufu
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMascarar(t *testing.T) {
+	casos := []struct{ obtido, esperado string }{
+		{MascararCpf("123.456.789-09"), "***.456.789-**"},
+		{MascararCpf("12345678909"), "***.456.789-**"},
+		{MascararCpf("123"), "***"},
+		{MascararCpf(""), ""},
+		{MascararRg("MG1234567"), "*******67"},
+		{MascararNome("Fulano de tal"), "Fulano T."},
+		{MascararNome("Fulano"), "Fulano"},
+		{MascararEmail("fulano@ufu.br"), "f***@ufu.br"},
+		{MascararEmail("sem-arroba"), "***"},
+		{MascararData(time.Date(2000, 2, 1, 0, 0, 0, 0, time.UTC)), "**/**/2000"},
+		{MascararIdUfu("https://www.sistemas.ufu.br/valida-ufu/#/id-digital/12352998224725"), "123***982247**"},
+	}
+	for _, c := range casos {
+		if c.obtido != c.esperado {
+			t.Errorf("esperado %q, obtido %q", c.esperado, c.obtido)
+		}
+	}
+}
+
+func TestDadosPessoaisNaoVazam(t *testing.T) {
+	email := "fulano@ufu.br"
+	sensiveis := []string{"52998224725", "MG1234567", "Maria Mãe", "João Pai", "segredo-token", "fulano@ufu.br", "QUJDREVG", "04/05/1999", "1999-05-04"}
+	id := IdUfu{ID: 1, Nome: "Fulano de Tal", CPF: "52998224725", RG: "MG1234567", NomeMae: "Maria Mãe", NomePai: "João Pai",
+		CodigoBarra: "12352998224725", Foto: "QUJDREVG", DataNascimento: time.Date(1999, 5, 4, 0, 0, 0, 0, fusoHorarioUfu).UnixMilli(), Situacao: 3}
+	valores := []any{
+		id,
+		&id,
+		*id.Identidade(),
+		IdentidadeDigital{Nome: "Fulano de Tal", Cpf: "529.982.247-25", Rg: "MG1234567", NomeMae: "Maria Mãe", Foto: "QUJDREVG"},
+		&DadosSSO{Nome: "Fulano de Tal", Cpf: "52998224725", Email: email, Chave: "segredo-token"},
+		DadosLoginMobile{Nome: "Fulano de Tal", Token: "segredo-token", Email: &email},
+	}
+
+	var log bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&log, nil))
+	for _, v := range valores {
+		texto := fmt.Sprintf("%v %+v %#v %s", v, v, v, v)
+		logger.Info("identidade", "dados", v)
+		texto += log.String()
+		for _, s := range sensiveis {
+			if strings.Contains(texto, s) {
+				t.Errorf("%T expôs %q: %s", v, s, texto)
+			}
+		}
+		if !strings.Contains(texto, "Fulano T.") {
+			t.Errorf("%T deveria mostrar o nome mascarado: %s", v, texto)
+		}
+		log.Reset()
+	}
+
+	if s := id.String(); !strings.Contains(s, "cpf=***.982.247-**") || !strings.Contains(s, "foto=\"[foto, 8 bytes]\"") {
+		t.Fatalf("formato inesperado: %s", s)
+	}
+}
+
+func TestProjetar(t *testing.T) {
+	identidade := (&IdentidadeDigital{Nome: "Fulano de Tal", Cpf: "52998224725", Matricula: "123", Vinculo: "Aluno", Situacao: "3"}).Identidade()
+	p := identidade.Projetar(FinalidadeEstatistica)
+	if len(p) != 3 || p["vinculo"] != "Aluno" || p["situacao"] != SituacaoAtiva {
+		t.Fatalf("projeção inesperada: %v", p)
+	}
+	if _, ok := identidade.Projetar(FinalidadeControleAcesso)["cpf"]; ok {
+		t.Fatal("o controle de acesso não deveria receber o CPF")
+	}
+	if identidade.Projetar(FinalidadeCompleta)["cpf"] != "52998224725" {
+		t.Fatal("a finalidade completa deveria receber o CPF")
+	}
+	if len(identidade.Projetar(Finalidade(99))) != 0 {
+		t.Fatal("finalidade desconhecida deveria retornar uma projeção vazia")
+	}
+}
+
+func TestIdDigitalRefMascarado(t *testing.T) {
+	ref, err := InterpretarIdDigital("12352998224725")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ref.IdCompleto() != "12352998224725" || ref.String() != "123***982247**" {
+		t.Fatalf("IdCompleto = %q, String = %q", ref.IdCompleto(), ref.String())
+	}
+	resultado := ResultadoLote{Entrada: ref.URL(), Id: ref.Id, IdUfu: &IdUfu{Nome: "Fulano de Tal", CPF: "52998224725"}, Err: ErrIdentidadeInativa}
+
+	var log bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&log, nil))
+	for _, v := range []any{ref, *ref, resultado, &resultado} {
+		texto := fmt.Sprintf("%v %+v %#v %s", v, v, v, v)
+		logger.Info("lote", "dados", v)
+		texto += log.String()
+		if strings.Contains(texto, "52998224725") {
+			t.Errorf("%T expôs o CPF: %s", v, texto)
+		}
+		if !strings.Contains(texto, "123***982247**") {
+			t.Errorf("%T deveria mostrar o id mascarado: %s", v, texto)
+		}
+		log.Reset()
+	}
+}
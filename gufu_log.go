@@ -0,0 +1,199 @@
+package gufu
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var (
+	Logger        *slog.Logger //Logger usado pela biblioteca. Se nil (padrão), nada é registrado. Os dados pessoais são sempre mascarados.
+	LogarPayloads = false      //Se true, registra no nível Debug o conteúdo descriptografado das requisições e respostas do aplicativo, com os dados pessoais mascarados.
+)
+
+// Servico identifica qual sistema da UFU recebe uma requisição.
+type Servico string
+
+const (
+	ServicoSSO    Servico = "sso"    //SSO da UFU (ssoUrl)
+	ServicoMobile Servico = "mobile" //API do aplicativo móvel, incluindo os cardápios (mobileApiUrl)
+	ServicoValida Servico = "valida" //API de validação das identidades digitais (validaApiUrl)
+)
+
+// Campos de um JSON que são mascarados ou omitidos nos payloads registrados por LogarPayloads.
+var camposSensiveis = map[string]func(string) string{
+	"cpf":            MascararCpf,
+	"rg":             MascararRg,
+	"nome":           MascararNome,
+	"nomePai":        mascararTudo,
+	"nomeMae":        mascararTudo,
+	"email":          MascararEmail,
+	"uid":            MascararEmail,
+	"login":          MascararEmail,
+	"codigoBarra":    MascararIdUfu,
+	"dataNascimento": mascararTudo,
+	"senha":          omitir,
+	"token":          omitir,
+	"chave":          omitir,
+	"foto":           descreverFoto,
+	"avatar":         descreverFoto,
+}
+
+type chaveTentativa struct{}
+
+// ComTentativa retorna um contexto que faz as requisições feitas com ele serem registradas no Logger como a tentativa n.
+// A biblioteca não repete requisições sozinha, então, sem ComTentativa, toda requisição é registrada como a tentativa 1;
+// programas que repetem as chamadas que falharam podem usar ComTentativa para distinguir as repetições nos logs.
+func ComTentativa(ctx context.Context, n int) context.Context {
+	return context.WithValue(ctx, chaveTentativa{}, n)
+}
+
+func tentativaDe(ctx context.Context) int {
+	if n, ok := ctx.Value(chaveTentativa{}).(int); ok && n > 0 {
+		return n
+	}
+	return 1
+}
+
+// Executa uma requisição com o ClienteHTTP, registrando no Logger o serviço, método, endpoint, tentativa, status e duração
+// e avisando a Instrumentacao do início e do fim da requisição. Se o disjuntor do serviço estiver aberto,
+// a requisição não é feita e o erro é ErrCircuitoAberto.
+// Apenas o caminho da URL é registrado, já que a query pode conter dados pessoais (Ex: o id ufu, que contém o CPF).
+// Se a requisição não tiver User-Agent, usa o userAgent padrão.
+func executar(req *http.Request, servico Servico) (*http.Response, error) {
+	if req.Header.Get("User-Agent") == "" {
+		req.Header.Set("User-Agent", userAgent)
+	}
+	info := InfoRequisicao{Servico: servico, Metodo: req.Method, Endpoint: req.URL.Path}
+	if Instrumentacao != nil {
+		req = req.WithContext(Instrumentacao.InicioRequisicao(req.Context(), info))
+	}
+	inicio := time.Now()
+	var (
+		res *http.Response
+		err error
+	)
+	registrar := func(bool) {}
+	if ignorar, _ := req.Context().Value(chaveIgnorarDisjuntor{}).(bool); !ignorar {
+		registrar, err = disjuntorDe(servico).permitir(servico)
+	}
+	if err == nil {
+		res, err = ClienteHTTP.Do(req)
+		registrar(falhaDoServico(res, err))
+	}
+	duracao := time.Since(inicio)
+	if Instrumentacao != nil {
+		resultado := ResultadoRequisicao{Duracao: duracao, Err: err}
+		if res != nil {
+			resultado.Status = res.StatusCode
+		}
+		resultado.TipoErro = classificarErro(resultado.Status, err)
+		Instrumentacao.FimRequisicao(req.Context(), info, resultado)
+	}
+
+	if Logger != nil {
+		atributos := []slog.Attr{
+			slog.String("servico", string(servico)),
+			slog.String("metodo", req.Method),
+			slog.String("endpoint", req.URL.Path),
+			slog.Int("tentativa", tentativaDe(req.Context())),
+			slog.Duration("duracao", duracao),
+		}
+		nivel := slog.LevelInfo
+		switch {
+		case err != nil:
+			nivel = slog.LevelWarn
+			atributos = append(atributos, slog.String("erro", err.Error()))
+		case res.StatusCode >= 500:
+			nivel = slog.LevelWarn
+			atributos = append(atributos, slog.Int("status", res.StatusCode))
+		default:
+			atributos = append(atributos, slog.Int("status", res.StatusCode))
+		}
+		Logger.LogAttrs(req.Context(), nivel, "gufu: requisição", atributos...)
+	}
+	return res, err
+}
+
+// Descriptografa o corpo da resposta de uma requisição ao aplicativo, registrando no Logger as falhas
+// e, se LogarPayloads estiver ativado, o conteúdo descriptografado.
+func descriptografarResposta(req *http.Request, servico Servico, corpo []byte) (string, error) {
+	texto, err := Descriptografar(string(corpo))
+	if err != nil {
+		if Logger != nil {
+			Logger.LogAttrs(req.Context(), slog.LevelError, "gufu: falha ao descriptografar a resposta",
+				slog.String("servico", string(servico)),
+				slog.String("endpoint", req.URL.Path),
+				slog.String("erro", err.Error()),
+			)
+		}
+		return texto, err
+	}
+	logarPayload(req, servico, "resposta", texto)
+	return texto, nil
+}
+
+// Registra no nível Debug o payload descriptografado de uma requisição ou resposta, se LogarPayloads estiver ativado.
+// direcao é "requisicao" ou "resposta". Os campos de camposSensiveis são mascarados antes de registrar.
+func logarPayload(req *http.Request, servico Servico, direcao, payload string) {
+	if Logger == nil || !LogarPayloads || !Logger.Enabled(req.Context(), slog.LevelDebug) {
+		return
+	}
+	Logger.LogAttrs(req.Context(), slog.LevelDebug, "gufu: payload",
+		slog.String("servico", string(servico)),
+		slog.String("endpoint", req.URL.Path),
+		slog.String("direcao", direcao),
+		slog.String("payload", MascararPayload(payload)),
+	)
+}
+
+// MascararPayload mascara os dados pessoais de um JSON (CPF, RG, nomes, e-mail, senha, tokens, fotos...),
+// inclusive em JSONs guardados como texto dentro de outro JSON, como o campo "body" das respostas do aplicativo.
+// Se o payload não for um JSON, retorna apenas o tamanho dele.
+func MascararPayload(payload string) string {
+	var valor any
+	if err := json.Unmarshal([]byte(payload), &valor); err != nil {
+		return fmt.Sprintf("[payload não JSON, %d bytes]", len(payload))
+	}
+	mascarado, err := json.Marshal(mascararValorJSON(valor))
+	if err != nil {
+		return omitido
+	}
+	return string(mascarado)
+}
+
+func mascararValorJSON(valor any) any {
+	switch v := valor.(type) {
+	case map[string]any:
+		for chave, item := range v {
+			if mascarar, ok := camposSensiveis[chave]; ok {
+				if texto, ok := item.(string); ok {
+					v[chave] = mascarar(texto)
+				} else if item != nil {
+					v[chave] = omitido
+				}
+				continue
+			}
+			v[chave] = mascararValorJSON(item)
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = mascararValorJSON(item)
+		}
+	case string:
+		//JSON dentro de uma string, como o "body" das respostas do aplicativo
+		if texto := strings.TrimSpace(v); strings.HasPrefix(texto, "{") || strings.HasPrefix(texto, "[") {
+			var interno any
+			if json.Unmarshal([]byte(texto), &interno) == nil {
+				if mascarado, err := json.Marshal(mascararValorJSON(interno)); err == nil {
+					return string(mascarado)
+				}
+			}
+		}
+	}
+	return valor
+}
@@ -0,0 +1,111 @@
+package gufu
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Ativa o Logger durante o teste, retornando o buffer onde os registros em JSON são escritos
+func capturarLogs(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	logger, payloads := Logger, LogarPayloads
+	Logger = slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	t.Cleanup(func() { Logger, LogarPayloads = logger, payloads })
+	return &buf
+}
+
+func registrosDeLog(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var registros []map[string]any
+	for _, linha := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var r map[string]any
+		if err := json.Unmarshal([]byte(linha), &r); err != nil {
+			t.Fatalf("registro inválido %q: %v", linha, err)
+		}
+		registros = append(registros, r)
+	}
+	return registros
+}
+
+func TestLogRequisicao(t *testing.T) {
+	servidor := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("User-Agent") != userAgent {
+			t.Errorf("User-Agent inesperado: %q", r.Header.Get("User-Agent"))
+		}
+		fmt.Fprint(w, `{"identidadeDigital":null,"dataNascimentoString":null}`)
+	}))
+	defer servidor.Close()
+	url := validaApiUrl
+	validaApiUrl = servidor.URL
+	ReiniciarDisjuntores()
+	defer func() { validaApiUrl = url }()
+
+	buf := capturarLogs(t)
+	ObterIdUfuComContexto(context.Background(), "12352998224725")
+	if strings.Contains(buf.String(), "52998224725") {
+		t.Fatalf("o id ufu (que contém o CPF) não deveria ser registrado: %s", buf)
+	}
+	r := registrosDeLog(t, buf)[0]
+	if r["servico"] != "valida" || r["metodo"] != "GET" || r["endpoint"] != "/id-digital/buscarDadosIdDigital" || r["status"] != 200.0 || r["duracao"] == nil || r["tentativa"] != 1.0 {
+		t.Fatalf("registro inesperado: %v", r)
+	}
+
+	buf.Reset()
+	ObterIdUfuComContexto(ComTentativa(context.Background(), 3), "12352998224725")
+	if r := registrosDeLog(t, buf)[0]; r["tentativa"] != 3.0 {
+		t.Fatalf("a tentativa de ComTentativa deveria ser registrada: %v", r)
+	}
+
+	buf.Reset()
+	servidor.Close()
+	ObterIdUfuComContexto(context.Background(), "12352998224725")
+	if r := registrosDeLog(t, buf)[0]; r["level"] != "WARN" || r["erro"] == nil {
+		t.Fatalf("falhas de conexão deveriam ser registradas como WARN: %v", r)
+	}
+}
+
+func TestLogPayload(t *testing.T) {
+	buf := capturarLogs(t)
+	req := httptest.NewRequest(http.MethodPost, "https://exemplo/identidade-digital/buscarByToken", nil)
+	resposta := `{"statusCodeValue":200,"body":"{\"nome\":\"Fulano de Tal\",\"cpf\":\"52998224725\",\"foto\":\"QUJD\",\"vinculo\":\"Aluno\"}"}`
+
+	logarPayload(req, ServicoMobile, "resposta", resposta)
+	if buf.Len() != 0 {
+		t.Fatal("payloads não deveriam ser registrados sem LogarPayloads")
+	}
+
+	LogarPayloads = true
+	logarPayload(req, ServicoMobile, "resposta", resposta)
+	r := registrosDeLog(t, buf)[0]
+	payload, _ := r["payload"].(string)
+	if r["level"] != "DEBUG" || strings.Contains(payload, "52998224725") || strings.Contains(payload, "QUJD") || !strings.Contains(payload, "Aluno") {
+		t.Fatalf("payload deveria ser registrado mascarado: %v", r)
+	}
+
+	buf.Reset()
+	if _, err := descriptografarResposta(req, ServicoMobile, []byte("!!!G2b1UFYMYjNViPZY6bSpvHnNYxHsenha")); err == nil {
+		t.Fatal("esperado erro ao descriptografar")
+	}
+	if r := registrosDeLog(t, buf)[0]; r["level"] != "ERROR" || r["endpoint"] != "/identidade-digital/buscarByToken" {
+		t.Fatalf("falha ao descriptografar deveria ser registrada: %v", r)
+	}
+}
+
+func TestMascararPayload(t *testing.T) {
+	obtido := MascararPayload(`{"login":"fulano@ufu.br","senha":"123","perfis":[{"cpf":"12345678909"}],"dataNascimento":946695600000}`)
+	esperado := `{"dataNascimento":"[omitido]","login":"f***@ufu.br","perfis":[{"cpf":"***.456.789-**"}],"senha":"[omitido]"}`
+	if obtido != esperado {
+		t.Fatalf("esperado %s, obtido %s", esperado, obtido)
+	}
+	if obtido := MascararPayload("não é json"); obtido != "[payload não JSON, 12 bytes]" {
+		t.Fatalf("obtido %s", obtido)
+	}
+}
@@ -0,0 +1,119 @@
+package gufu
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// OpcoesLote contém as configurações usadas em ValidarIdsEmLote. Os campos com valor zero usam os valores padrão.
+type OpcoesLote struct {
+	Concorrencia          int                                                  //Quantidade máxima de consultas simultâneas. Padrão: 4
+	RequisicoesPorSegundo float64                                              //Limite de consultas por segundo, somando todas as consultas simultâneas. Zero desativa o limite
+	Progresso             func(concluidos, total int)                          //Chamada, sem concorrência, sempre que um id termina de ser consultado. total é a quantidade de ids distintos
+	Buscar                func(ctx context.Context, id string) (*IdUfu, error) //Função usada para consultar cada id. Padrão: ObterIdUfuComContexto. Um retorno (nil, nil) é tratado como ErrIdUfuNaoEncontrado
+	Agora                 time.Time                                            //Instante usado para verificar a validade das identidades. Padrão: o início do lote
+}
+
+// ResultadoLote é o resultado da validação de um dos ids passados para ValidarIdsEmLote.
+// Entrada e Id contêm o CPF: "%v" e slog mostram os dois mascarados, mas os campos guardam os valores completos.
+type ResultadoLote struct {
+	Entrada string //Id, URL ou conteúdo do QR Code como foi informado
+	Id      string //Id normalizado por InterpretarIdDigital. Vazio se a entrada for inválida
+	IdUfu   *IdUfu //Dados da identidade digital. Nil se a consulta falhou
+	Valida  bool   //Se a identidade digital foi encontrada e está válida (veja Identidade.IsValida)
+	Err     error  //Motivo da identidade não ser válida: entrada inválida, falha na consulta ou o erro retornado por IsValida
+}
+
+// ValidarIdsEmLote consulta e valida vários ids ufu (ou URLs e conteúdos de QR Code) ao mesmo tempo, usando ObterIdUfuComContexto.
+// Os ids são normalizados com InterpretarIdDigital e cada id distinto é consultado apenas uma vez. As consultas são feitas por
+// um número limitado de goroutines e podem ter a taxa limitada em opcoes. Se opcoes for nil, são usados os valores padrão.
+// Retorna um resultado para cada entrada, na mesma ordem de ids. Se ctx for cancelado, as consultas pendentes recebem o erro
+// do contexto, e ele também é retornado.
+func ValidarIdsEmLote(ctx context.Context, ids []string, opcoes *OpcoesLote) ([]ResultadoLote, error) {
+	var o OpcoesLote
+	if opcoes != nil {
+		o = *opcoes
+	}
+	if o.Concorrencia <= 0 {
+		o.Concorrencia = 4
+	}
+	if o.Buscar == nil {
+		o.Buscar = ObterIdUfuComContexto
+	}
+	if o.Agora.IsZero() {
+		o.Agora = time.Now()
+	}
+
+	resultados := make([]ResultadoLote, len(ids))
+	posicoes := map[string][]int{} //Posições em resultados de cada id distinto
+	var unicos []string
+	for i, entrada := range ids {
+		resultados[i].Entrada = entrada
+		ref, err := InterpretarIdDigital(entrada)
+		if err != nil {
+			resultados[i].Err = err
+			continue
+		}
+		resultados[i].Id = ref.Id
+		if _, ok := posicoes[ref.Id]; !ok {
+			unicos = append(unicos, ref.Id)
+		}
+		posicoes[ref.Id] = append(posicoes[ref.Id], i)
+	}
+
+	var limite <-chan time.Time
+	if o.RequisicoesPorSegundo > 0 {
+		//Acima de 1e9 consultas por segundo o intervalo arredondaria para zero, que time.NewTicker não aceita
+		ticker := time.NewTicker(max(time.Duration(float64(time.Second)/o.RequisicoesPorSegundo), time.Nanosecond))
+		defer ticker.Stop()
+		limite = ticker.C
+	}
+
+	var (
+		mu         sync.Mutex
+		concluidos int
+		wg         sync.WaitGroup
+		fila       = make(chan string)
+	)
+	for range min(o.Concorrencia, len(unicos)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for id := range fila {
+				r := ResultadoLote{Id: id}
+				if limite != nil {
+					select {
+					case <-limite:
+					case <-ctx.Done():
+					}
+				}
+				if r.Err = ctx.Err(); r.Err == nil {
+					r.IdUfu, r.Err = o.Buscar(ctx, id)
+					if r.Err == nil && r.IdUfu == nil {
+						r.Err = ErrIdUfuNaoEncontrado
+					}
+				}
+				if r.Err == nil {
+					r.Valida, r.Err = r.IdUfu.IsValida(o.Agora)
+				}
+
+				mu.Lock()
+				for _, i := range posicoes[id] {
+					resultados[i].IdUfu, resultados[i].Valida, resultados[i].Err = r.IdUfu, r.Valida, r.Err
+				}
+				concluidos++
+				if o.Progresso != nil {
+					o.Progresso(concluidos, len(unicos))
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, id := range unicos {
+		fila <- id
+	}
+	close(fila)
+	wg.Wait()
+	return resultados, ctx.Err()
+}
@@ -0,0 +1,123 @@
+package gufu
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestValidarIdsEmLote(t *testing.T) {
+	var consultas atomic.Int32
+	servidor := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		consultas.Add(1)
+		id := r.URL.Query().Get("idIdentidade")
+		switch id {
+		case "12352998224725":
+			fmt.Fprintf(w, `{"identidadeDigital":{"id":1,"nome":"Fulano de Tal","situacao":3,"codigoBarra":"%s"},"dataNascimentoString":"01/01/2000"}`, id)
+		case "12311144477735":
+			fmt.Fprint(w, `{"identidadeDigital":{"id":2,"nome":"Ciclano","situacao":5,"situacaoDescricao":"Cancelada"},"dataNascimentoString":"01/01/2000"}`)
+		default:
+			fmt.Fprint(w, `{"identidadeDigital":null,"documentoArquivoTOResult":null,"dataNascimentoString":null}`)
+		}
+	}))
+	defer servidor.Close()
+	url := validaApiUrl
+	validaApiUrl = servidor.URL
+	ReiniciarDisjuntores()
+	defer func() { validaApiUrl = url }()
+
+	ids := []string{
+		"12352998224725",
+		"https://www.sistemas.ufu.br/valida-ufu/#/id-digital/12352998224725",
+		"12311144477735",
+		"12312345678909",
+		"abc",
+	}
+	var progresso []int
+	resultados, err := ValidarIdsEmLote(context.Background(), ids, &OpcoesLote{
+		Concorrencia: 2,
+		Progresso:    func(concluidos, total int) { progresso = append(progresso, concluidos*10+total) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if consultas.Load() != 3 {
+		t.Fatalf("ids repetidos deveriam ser consultados uma vez, %d consultas", consultas.Load())
+	}
+	if fmt.Sprint(progresso) != "[13 23 33]" {
+		t.Fatalf("progresso inesperado: %v", progresso)
+	}
+	for i, r := range resultados[:2] {
+		if !r.Valida || r.Err != nil || r.IdUfu == nil || r.IdUfu.Nome != "Fulano de Tal" || r.Entrada != ids[i] {
+			t.Fatalf("resultado %d inesperado: %+v", i, r)
+		}
+	}
+	if r := resultados[2]; r.Valida || !errors.Is(r.Err, ErrIdentidadeInativa) || r.IdUfu == nil {
+		t.Fatalf("identidade cancelada deveria ser inválida: %+v", r)
+	}
+	if r := resultados[3]; !errors.Is(r.Err, ErrIdUfuNaoEncontrado) {
+		t.Fatalf("identidade inexistente deveria retornar ErrIdUfuNaoEncontrado: %+v", r)
+	}
+	if r := resultados[4]; !errors.Is(r.Err, ErrIdUfuNaoNumerico) || r.Id != "" {
+		t.Fatalf("entrada inválida não deveria ser consultada: %+v", r)
+	}
+}
+
+func TestValidarIdsEmLoteConcorrencia(t *testing.T) {
+	var ids []string
+	for i := 0; i < 20; i++ {
+		ids = append(ids, fmt.Sprintf("1%02d52998224725", i))
+	}
+	var (
+		mu             sync.Mutex
+		ativas, maximo int
+	)
+	buscar := func(ctx context.Context, id string) (*IdUfu, error) {
+		mu.Lock()
+		ativas++
+		maximo = max(maximo, ativas)
+		mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+		mu.Lock()
+		ativas--
+		mu.Unlock()
+		return &IdUfu{Situacao: int(SituacaoAtiva)}, nil
+	}
+	if _, err := ValidarIdsEmLote(context.Background(), ids, &OpcoesLote{Concorrencia: 3, Buscar: buscar}); err != nil {
+		t.Fatal(err)
+	}
+	if maximo != 3 {
+		t.Fatalf("esperado no máximo 3 consultas simultâneas, obtido %d", maximo)
+	}
+
+	inicio := time.Now()
+	if _, err := ValidarIdsEmLote(context.Background(), ids[:5], &OpcoesLote{Concorrencia: 5, RequisicoesPorSegundo: 100, Buscar: buscar}); err != nil {
+		t.Fatal(err)
+	}
+	if decorrido := time.Since(inicio); decorrido < 45*time.Millisecond {
+		t.Fatalf("o limite de 100 consultas por segundo não foi respeitado: %v", decorrido)
+	}
+
+	if _, err := ValidarIdsEmLote(context.Background(), ids[:2], &OpcoesLote{RequisicoesPorSegundo: 2e9, Buscar: buscar}); err != nil {
+		t.Fatal(err)
+	}
+
+	nada := func(ctx context.Context, id string) (*IdUfu, error) { return nil, nil }
+	resultados, err := ValidarIdsEmLote(context.Background(), ids[:1], &OpcoesLote{Buscar: nada})
+	if err != nil || !errors.Is(resultados[0].Err, ErrIdUfuNaoEncontrado) || resultados[0].Valida {
+		t.Fatalf("esperado ErrIdUfuNaoEncontrado, obtido %+v, %v", resultados[0], err)
+	}
+
+	ctx, cancelar := context.WithCancel(context.Background())
+	cancelar()
+	resultados, err = ValidarIdsEmLote(ctx, ids, &OpcoesLote{Buscar: buscar})
+	if !errors.Is(err, context.Canceled) || !errors.Is(resultados[0].Err, context.Canceled) {
+		t.Fatalf("esperado context.Canceled, obtido %v %v", err, resultados[0].Err)
+	}
+}
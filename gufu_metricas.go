@@ -0,0 +1,270 @@
+package gufu
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var Instrumentacao Instrumentador //Recebe o início e o fim de todas as requisições feitas pela biblioteca. Se nil (padrão), nada é medido.
+
+// InfoRequisicao identifica uma requisição feita pela biblioteca. Endpoint é apenas o caminho da URL, sem a query.
+type InfoRequisicao struct {
+	Servico  Servico
+	Metodo   string
+	Endpoint string
+}
+
+// TipoErro classifica o resultado de uma requisição, para ser usado em métricas com poucos valores possíveis.
+type TipoErro string
+
+const (
+	TipoErroNenhum    TipoErro = ""                //A requisição terminou com status 2xx ou 3xx
+	TipoErroTimeout   TipoErro = "timeout"         //O tempo limite do ClienteHTTP ou do contexto acabou
+	TipoErroCancelado TipoErro = "cancelado"       //O contexto foi cancelado
+	TipoErroRede      TipoErro = "rede"            //Falha de conexão, DNS, TLS etc
+	TipoErroHttp4xx   TipoErro = "http_4xx"        //O servidor respondeu com status 4xx
+	TipoErroHttp5xx   TipoErro = "http_5xx"        //O servidor respondeu com status 5xx
+	TipoErroCircuito  TipoErro = "circuito_aberto" //A requisição não foi feita porque o disjuntor do serviço está aberto
+)
+
+// ResultadoRequisicao é o resultado de uma requisição, passado para Instrumentador.FimRequisicao.
+type ResultadoRequisicao struct {
+	Status   int           //Status HTTP. Zero se a requisição falhou antes da resposta
+	Duracao  time.Duration //Tempo até receber os cabeçalhos da resposta
+	TipoErro TipoErro      //Classificação do erro, vazio se a requisição foi bem sucedida
+	Err      error         //Erro retornado pelo ClienteHTTP, se houver
+}
+
+// Instrumentador recebe o início e o fim de cada requisição feita pela biblioteca, incluindo os logins.
+// O contexto retornado por InicioRequisicao é usado na requisição e passado para FimRequisicao, o que permite
+// guardar nele um span de rastreamento, por exemplo. As implementações devem ser seguras para uso concorrente.
+type Instrumentador interface {
+	InicioRequisicao(ctx context.Context, info InfoRequisicao) context.Context
+	FimRequisicao(ctx context.Context, info InfoRequisicao, resultado ResultadoRequisicao)
+}
+
+// Instrumentadores combina vários Instrumentador, chamados na ordem do slice.
+type Instrumentadores []Instrumentador
+
+func (is Instrumentadores) InicioRequisicao(ctx context.Context, info InfoRequisicao) context.Context {
+	for _, i := range is {
+		ctx = i.InicioRequisicao(ctx, info)
+	}
+	return ctx
+}
+
+func (is Instrumentadores) FimRequisicao(ctx context.Context, info InfoRequisicao, resultado ResultadoRequisicao) {
+	for _, i := range is {
+		i.FimRequisicao(ctx, info, resultado)
+	}
+}
+
+// Classifica o erro ou o status de uma requisição.
+func classificarErro(status int, err error) TipoErro {
+	var erroRede net.Error
+	switch {
+	case err == nil && status >= 500:
+		return TipoErroHttp5xx
+	case err == nil && status >= 400:
+		return TipoErroHttp4xx
+	case err == nil:
+		return TipoErroNenhum
+	case errors.Is(err, ErrCircuitoAberto):
+		return TipoErroCircuito
+	case errors.Is(err, context.Canceled):
+		return TipoErroCancelado
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, os.ErrDeadlineExceeded),
+		errors.As(err, &erroRede) && erroRede.Timeout():
+		return TipoErroTimeout
+	}
+	return TipoErroRede
+}
+
+// MetricasPrometheus é um Instrumentador que guarda contadores e histogramas das requisições e os expõe no formato
+// de texto do Prometheus. Implementa http.Handler, então pode ser registrado diretamente (Ex: http.Handle("/metrics", m)).
+// Use NovasMetricasPrometheus para criar.
+type MetricasPrometheus struct {
+	mu          sync.Mutex
+	limites     []float64                 //Limites superiores dos buckets do histograma, em segundos
+	requisicoes map[[3]string]uint64      //servico, endpoint, status
+	erros       map[[3]string]uint64      //servico, endpoint, tipo
+	duracoes    map[[2]string]*histograma //servico, endpoint
+	emAndamento map[string]int64          //servico
+}
+
+type histograma struct {
+	buckets []uint64
+	soma    float64
+	total   uint64
+}
+
+// LimitesPadraoPrometheus são os limites, em segundos, dos buckets do histograma de duração usados quando nenhum é informado.
+var LimitesPadraoPrometheus = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 15}
+
+// NovasMetricasPrometheus cria um MetricasPrometheus com os limites (em segundos) dos buckets do histograma de duração.
+// Sem limites, usa LimitesPadraoPrometheus.
+func NovasMetricasPrometheus(limites ...float64) *MetricasPrometheus {
+	if len(limites) == 0 {
+		limites = LimitesPadraoPrometheus
+	}
+	limites = append([]float64(nil), limites...)
+	sort.Float64s(limites)
+	return &MetricasPrometheus{
+		limites:     limites,
+		requisicoes: map[[3]string]uint64{},
+		erros:       map[[3]string]uint64{},
+		duracoes:    map[[2]string]*histograma{},
+		emAndamento: map[string]int64{},
+	}
+}
+
+func (m *MetricasPrometheus) InicioRequisicao(ctx context.Context, info InfoRequisicao) context.Context {
+	m.mu.Lock()
+	m.emAndamento[string(info.Servico)]++
+	m.mu.Unlock()
+	return ctx
+}
+
+func (m *MetricasPrometheus) FimRequisicao(ctx context.Context, info InfoRequisicao, resultado ResultadoRequisicao) {
+	servico := string(info.Servico)
+	status := "erro"
+	if resultado.Status != 0 {
+		status = strconv.Itoa(resultado.Status)
+	}
+	segundos := resultado.Duracao.Seconds()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.emAndamento[servico]--
+	m.requisicoes[[3]string{servico, info.Endpoint, status}]++
+	if resultado.TipoErro != TipoErroNenhum {
+		m.erros[[3]string{servico, info.Endpoint, string(resultado.TipoErro)}]++
+	}
+	h := m.duracoes[[2]string{servico, info.Endpoint}]
+	if h == nil {
+		h = &histograma{buckets: make([]uint64, len(m.limites))}
+		m.duracoes[[2]string{servico, info.Endpoint}] = h
+	}
+	for i, limite := range m.limites {
+		if segundos <= limite {
+			h.buckets[i]++
+		}
+	}
+	h.soma += segundos
+	h.total++
+}
+
+// ServeHTTP responde com as métricas no formato de texto do Prometheus (versão 0.0.4).
+func (m *MetricasPrometheus) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.EscreverMetricas(w)
+}
+
+// EscreverMetricas escreve as métricas em w no formato de texto do Prometheus.
+func (m *MetricasPrometheus) EscreverMetricas(w io.Writer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var b strings.Builder
+
+	b.WriteString("# HELP gufu_requisicoes_total Quantidade de requisições feitas aos sistemas da UFU.\n# TYPE gufu_requisicoes_total counter\n")
+	for _, chave := range chavesOrdenadas(m.requisicoes) {
+		fmt.Fprintf(&b, "gufu_requisicoes_total{servico=%s,endpoint=%s,status=%s} %d\n", rotulo(chave[0]), rotulo(chave[1]), rotulo(chave[2]), m.requisicoes[chave])
+	}
+
+	b.WriteString("# HELP gufu_requisicoes_erros_total Quantidade de requisições que falharam, por tipo de erro.\n# TYPE gufu_requisicoes_erros_total counter\n")
+	for _, chave := range chavesOrdenadas(m.erros) {
+		fmt.Fprintf(&b, "gufu_requisicoes_erros_total{servico=%s,endpoint=%s,tipo=%s} %d\n", rotulo(chave[0]), rotulo(chave[1]), rotulo(chave[2]), m.erros[chave])
+	}
+
+	b.WriteString("# HELP gufu_requisicao_duracao_segundos Duração das requisições até receber os cabeçalhos da resposta.\n# TYPE gufu_requisicao_duracao_segundos histogram\n")
+	for _, chave := range chavesOrdenadas(m.duracoes) {
+		h := m.duracoes[chave]
+		rotulos := fmt.Sprintf("servico=%s,endpoint=%s", rotulo(chave[0]), rotulo(chave[1]))
+		for i, limite := range m.limites {
+			fmt.Fprintf(&b, "gufu_requisicao_duracao_segundos_bucket{%s,le=\"%s\"} %d\n", rotulos, strconv.FormatFloat(limite, 'g', -1, 64), h.buckets[i])
+		}
+		fmt.Fprintf(&b, "gufu_requisicao_duracao_segundos_bucket{%s,le=\"+Inf\"} %d\n", rotulos, h.total)
+		fmt.Fprintf(&b, "gufu_requisicao_duracao_segundos_sum{%s} %s\n", rotulos, strconv.FormatFloat(h.soma, 'g', -1, 64))
+		fmt.Fprintf(&b, "gufu_requisicao_duracao_segundos_count{%s} %d\n", rotulos, h.total)
+	}
+
+	b.WriteString("# HELP gufu_requisicoes_em_andamento Quantidade de requisições aguardando resposta.\n# TYPE gufu_requisicoes_em_andamento gauge\n")
+	for _, servico := range chavesOrdenadas(m.emAndamento) {
+		fmt.Fprintf(&b, "gufu_requisicoes_em_andamento{servico=%s} %d\n", rotulo(servico), m.emAndamento[servico])
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Formata o valor de um rótulo do Prometheus, com as aspas e os escapes necessários.
+func rotulo(valor string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(valor) + `"`
+}
+
+func chavesOrdenadas[K [3]string | [2]string | string, V any](m map[K]V) []K {
+	chaves := make([]K, 0, len(m))
+	for k := range m {
+		chaves = append(chaves, k)
+	}
+	sort.Slice(chaves, func(i, j int) bool { return fmt.Sprint(chaves[i]) < fmt.Sprint(chaves[j]) })
+	return chaves
+}
+
+// Rastreador cria spans de rastreamento. É compatível com o OpenTelemetry: um trace.Tracer pode ser adaptado
+// com poucas linhas (veja o README), sem que a biblioteca dependa do OpenTelemetry.
+type Rastreador interface {
+	IniciarSpan(ctx context.Context, nome string) (context.Context, Span)
+}
+
+// Span é um trecho de rastreamento, equivalente ao trace.Span do OpenTelemetry.
+type Span interface {
+	DefinirAtributo(chave string, valor any) //Equivalente a SetAttributes
+	RegistrarErro(err error)                 //Equivalente a RecordError seguido de SetStatus(codes.Error, ...)
+	Finalizar()                              //Equivalente a End
+}
+
+type chaveSpan struct{}
+
+// InstrumentadorRastreamento é um Instrumentador que cria um span para cada requisição, com os atributos
+// das convenções semânticas de HTTP do OpenTelemetry (http.request.method, url.path, http.response.status_code e error.type).
+type InstrumentadorRastreamento struct {
+	Rastreador Rastreador
+}
+
+func (i InstrumentadorRastreamento) InicioRequisicao(ctx context.Context, info InfoRequisicao) context.Context {
+	ctx, span := i.Rastreador.IniciarSpan(ctx, info.Metodo+" "+info.Endpoint)
+	span.DefinirAtributo("http.request.method", info.Metodo)
+	span.DefinirAtributo("url.path", info.Endpoint)
+	span.DefinirAtributo("gufu.servico", string(info.Servico))
+	return context.WithValue(ctx, chaveSpan{}, span)
+}
+
+func (i InstrumentadorRastreamento) FimRequisicao(ctx context.Context, info InfoRequisicao, resultado ResultadoRequisicao) {
+	span, ok := ctx.Value(chaveSpan{}).(Span)
+	if !ok {
+		return
+	}
+	if resultado.Status != 0 {
+		span.DefinirAtributo("http.response.status_code", resultado.Status)
+	}
+	if resultado.TipoErro != TipoErroNenhum {
+		span.DefinirAtributo("error.type", string(resultado.TipoErro))
+		err := resultado.Err
+		if err == nil {
+			err = fmt.Errorf("status http %d", resultado.Status)
+		}
+		span.RegistrarErro(err)
+	}
+	span.Finalizar()
+}
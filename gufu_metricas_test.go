@@ -0,0 +1,119 @@
+package gufu
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type spanTeste struct {
+	nome       string
+	atributos  map[string]any
+	erro       error
+	finalizado bool
+}
+
+func (s *spanTeste) DefinirAtributo(chave string, valor any) { s.atributos[chave] = valor }
+func (s *spanTeste) RegistrarErro(err error)                 { s.erro = err }
+func (s *spanTeste) Finalizar()                              { s.finalizado = true }
+
+type rastreadorTeste struct {
+	mu    sync.Mutex
+	spans []*spanTeste
+}
+
+func (r *rastreadorTeste) IniciarSpan(ctx context.Context, nome string) (context.Context, Span) {
+	span := &spanTeste{nome: nome, atributos: map[string]any{}}
+	r.mu.Lock()
+	r.spans = append(r.spans, span)
+	r.mu.Unlock()
+	return ctx, span
+}
+
+func TestInstrumentacao(t *testing.T) {
+	servidor := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("idIdentidade") == "12311144477735" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		fmt.Fprint(w, `{"identidadeDigital":{"id":1},"dataNascimentoString":"01/01/2000"}`)
+	}))
+	defer servidor.Close()
+	url := validaApiUrl
+	validaApiUrl = servidor.URL
+	ReiniciarDisjuntores()
+	defer func() { validaApiUrl = url }()
+
+	metricas := NovasMetricasPrometheus(0.5, 0.1)
+	rastreador := &rastreadorTeste{}
+	Instrumentacao = Instrumentadores{metricas, InstrumentadorRastreamento{Rastreador: rastreador}}
+	defer func() { Instrumentacao = nil }()
+
+	ObterIdUfu("12352998224725")
+	ObterIdUfu("12352998224725")
+	ObterIdUfu("12311144477735")
+
+	resposta := httptest.NewRecorder()
+	metricas.ServeHTTP(resposta, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	texto := resposta.Body.String()
+	for _, esperado := range []string{
+		`gufu_requisicoes_total{servico="valida",endpoint="/id-digital/buscarDadosIdDigital",status="200"} 2`,
+		`gufu_requisicoes_total{servico="valida",endpoint="/id-digital/buscarDadosIdDigital",status="500"} 1`,
+		`gufu_requisicoes_erros_total{servico="valida",endpoint="/id-digital/buscarDadosIdDigital",tipo="http_5xx"} 1`,
+		`gufu_requisicao_duracao_segundos_bucket{servico="valida",endpoint="/id-digital/buscarDadosIdDigital",le="0.1"} 3`,
+		`gufu_requisicao_duracao_segundos_bucket{servico="valida",endpoint="/id-digital/buscarDadosIdDigital",le="+Inf"} 3`,
+		`gufu_requisicao_duracao_segundos_count{servico="valida",endpoint="/id-digital/buscarDadosIdDigital"} 3`,
+		`gufu_requisicoes_em_andamento{servico="valida"} 0`,
+		"# TYPE gufu_requisicao_duracao_segundos histogram",
+	} {
+		if !strings.Contains(texto, esperado) {
+			t.Errorf("%q não encontrado em:\n%s", esperado, texto)
+		}
+	}
+	if !strings.HasPrefix(resposta.Header().Get("Content-Type"), "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type inesperado: %q", resposta.Header().Get("Content-Type"))
+	}
+
+	if len(rastreador.spans) != 3 {
+		t.Fatalf("esperado um span por requisição, obtido %d", len(rastreador.spans))
+	}
+	ok, falha := rastreador.spans[0], rastreador.spans[2]
+	if ok.nome != "GET /id-digital/buscarDadosIdDigital" || !ok.finalizado || ok.erro != nil || ok.atributos["http.response.status_code"] != 200 {
+		t.Fatalf("span inesperado: %+v", ok)
+	}
+	if !falha.finalizado || falha.erro == nil || falha.atributos["error.type"] != "http_5xx" {
+		t.Fatalf("span de erro inesperado: %+v", falha)
+	}
+}
+
+func TestClassificarErro(t *testing.T) {
+	casos := []struct {
+		status   int
+		err      error
+		esperado TipoErro
+	}{
+		{200, nil, TipoErroNenhum},
+		{404, nil, TipoErroHttp4xx},
+		{503, nil, TipoErroHttp5xx},
+		{0, fmt.Errorf("Get: %w", context.Canceled), TipoErroCancelado},
+		{0, fmt.Errorf("Get: %w", os.ErrDeadlineExceeded), TipoErroTimeout},
+		{0, errors.New("connection refused"), TipoErroRede},
+	}
+	for _, c := range casos {
+		if obtido := classificarErro(c.status, c.err); obtido != c.esperado {
+			t.Errorf("%d %v: esperado %q, obtido %q", c.status, c.err, c.esperado, obtido)
+		}
+	}
+}
+
+func TestRotuloPrometheus(t *testing.T) {
+	if r := rotulo("a\"b\\c\nd"); r != `"a\"b\\c\nd"` {
+		t.Fatalf("escape inesperado: %s", r)
+	}
+}
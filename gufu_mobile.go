@@ -0,0 +1,131 @@
+package gufu
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+var ErrRespostaVaziaMobile = errors.New("a api do aplicativo retornou uma resposta vazia") //Erro retornado por CallMobile quando a resposta descriptografada, ou o body do envelope, é "{}", null ou vazio.
+
+// ErroStatusMobile é retornado por CallMobile quando a API do aplicativo responde com um status http diferente de 200
+// ou quando o envelope {body, statusCode, statusCodeValue} da resposta indica uma falha.
+type ErroStatusMobile struct {
+	Endpoint string //Endpoint chamado (Ex: /identidade-digital/buscarByToken)
+	Status   int    //Status http ou statusCodeValue do envelope
+	Mensagem string //Mensagem de erro retornada pela API, se houver
+}
+
+func (e *ErroStatusMobile) Error() string {
+	if e.Mensagem != "" {
+		return fmt.Sprintf("algo deu errado ao chamar %s: %v, status http: %v", e.Endpoint, e.Mensagem, e.Status)
+	}
+	return fmt.Sprintf("algo deu errado ao chamar %s, status http: %v", e.Endpoint, e.Status)
+}
+
+// Envelope usado por alguns endpoints do aplicativo, com a resposta real em body (como texto ou como JSON)
+type envelopeMobile struct {
+	Body            json.RawMessage `json:"body"`
+	StatusCode      json.RawMessage `json:"statusCode"`
+	StatusCodeValue *int            `json:"statusCodeValue"`
+}
+
+// CallMobile chama um endpoint da API do aplicativo móvel da UFU: codifica req em JSON, criptografa com o CodecPadrao,
+// envia com POST para mobileApiUrl+endpoint e descriptografa e decodifica a resposta em Resp.
+// autorizacao é o valor do cabeçalho Authorization (Ex: "Basic ..."); se vazio, é usada a credencial do endpoint
+// nas credenciais de ComCredenciaisApp ou em Credenciais e, se o endpoint não tiver uma, retorna ErrSemCredencialApp.
+// Respostas no envelope {body, statusCode, statusCodeValue} são desembrulhadas: um statusCodeValue diferente de 200
+// retorna *ErroStatusMobile e o conteúdo de body (mesmo se for um JSON guardado como texto) é decodificado em Resp.
+// Uma resposta (ou body) "{}", null ou vazia retorna ErrRespostaVaziaMobile e uma resposta que não pode ser decodificada,
+// ErrRespostaInvalidaServidor.
+func CallMobile[Req, Resp any](ctx context.Context, endpoint string, req Req, autorizacao string) (*Resp, error) {
+	if autorizacao == "" {
+		var err error
+		if autorizacao, err = autorizacaoDoEndpoint(ctx, endpoint); err != nil {
+			return nil, err
+		}
+	}
+	dados, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	corpo, err := Criptografar(string(dados))
+	if err != nil {
+		return nil, err
+	}
+
+	requisicao, err := http.NewRequestWithContext(ctx, http.MethodPost, mobileApiUrl+endpoint, strings.NewReader(corpo))
+	if err != nil {
+		return nil, fmt.Errorf("erro ao criar requisição: %v", err)
+	}
+	requisicao.Header.Add("Authorization", autorizacao)
+	requisicao.Header.Add("Content-Type", "application/json")
+	logarPayload(requisicao, ServicoMobile, "requisicao", string(dados))
+
+	resposta, err := executar(requisicao, ServicoMobile)
+	if err != nil {
+		return nil, err
+	}
+	defer resposta.Body.Close()
+	corpoResposta, err := io.ReadAll(resposta.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resposta.StatusCode != http.StatusOK {
+		var erro ErrorMobile
+		json.Unmarshal(corpoResposta, &erro)
+		return nil, &ErroStatusMobile{Endpoint: endpoint, Status: resposta.StatusCode, Mensagem: erro.Message}
+	}
+
+	texto, err := descriptografarResposta(requisicao, ServicoMobile, corpoResposta)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao descriptografar a resposta de %s: %w", endpoint, err)
+	}
+	if respostaVaziaMobile([]byte(texto)) {
+		return nil, ErrRespostaVaziaMobile
+	}
+
+	conteudo, err := desembrulharRespostaMobile(endpoint, []byte(texto))
+	if err != nil {
+		return nil, err
+	}
+	var resultado Resp
+	if err := json.Unmarshal(conteudo, &resultado); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrRespostaInvalidaServidor, err)
+	}
+	return &resultado, nil
+}
+
+// Retorna o conteúdo de body se a resposta estiver no envelope {body, statusCode, statusCodeValue},
+// ou a própria resposta caso contrário.
+func desembrulharRespostaMobile(endpoint string, texto []byte) ([]byte, error) {
+	var envelope envelopeMobile
+	if json.Unmarshal(texto, &envelope) != nil || envelope.StatusCodeValue == nil || envelope.Body == nil {
+		return texto, nil
+	}
+	if *envelope.StatusCodeValue != http.StatusOK {
+		return nil, &ErroStatusMobile{Endpoint: endpoint, Status: *envelope.StatusCodeValue}
+	}
+	conteudo := []byte(envelope.Body)
+	var interno string
+	if json.Unmarshal(envelope.Body, &interno) == nil {
+		//O body é um JSON guardado como texto
+		conteudo = []byte(interno)
+	}
+	if respostaVaziaMobile(conteudo) {
+		return nil, ErrRespostaVaziaMobile
+	}
+	return conteudo, nil
+}
+
+// Indica se a resposta é "{}", null ou vazia, que a api retorna quando não há dados
+func respostaVaziaMobile(texto []byte) bool {
+	texto = bytes.TrimSpace(texto)
+	return len(texto) == 0 || string(texto) == "null" || string(texto) == "{}"
+}
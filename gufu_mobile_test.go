@@ -0,0 +1,143 @@
+package gufu
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Emula a API do aplicativo: descriptografa a requisição e criptografa a resposta retornada por responder
+func servidorMobile(t *testing.T, responder func(r *http.Request, requisicao string) (int, string)) *httptest.Server {
+	t.Helper()
+	servidor := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		corpo, _ := io.ReadAll(r.Body)
+		requisicao, err := DescriptografarRequisicao(string(corpo))
+		if err != nil {
+			t.Errorf("requisição inválida: %v", err)
+		}
+		status, resposta := responder(r, requisicao)
+		if status != http.StatusOK {
+			w.WriteHeader(status)
+			fmt.Fprint(w, resposta)
+			return
+		}
+		cifrado, err := CriptografarResposta(resposta)
+		if err != nil {
+			t.Error(err)
+		}
+		fmt.Fprint(w, cifrado)
+	}))
+	t.Cleanup(servidor.Close)
+	apontarApisPara(t, servidor)
+	return servidor
+}
+
+func TestCallMobile(t *testing.T) {
+	type requisicao struct {
+		Token string `json:"token"`
+	}
+	type resposta struct {
+		Nome string `json:"nome"`
+	}
+	servidorMobile(t, func(r *http.Request, corpo string) (int, string) {
+		if r.Header.Get("Authorization") != "Basic teste" || r.Method != http.MethodPost {
+			t.Errorf("requisição inesperada: %v %v", r.Method, r.Header)
+		}
+		switch r.URL.Path {
+		case "/mobile/direto":
+			if corpo != `{"token":"abc"}` {
+				t.Errorf("corpo inesperado: %s", corpo)
+			}
+			return http.StatusOK, `{"nome":"Fulano"}`
+		case "/mobile/texto":
+			return http.StatusOK, `{"body":"{\"nome\":\"Ciclano\"}","statusCode":"OK","statusCodeValue":200}`
+		case "/mobile/objeto":
+			return http.StatusOK, `{"body":{"nome":"Beltrano"},"statusCode":"OK","statusCodeValue":200}`
+		case "/mobile/falha-envelope":
+			return http.StatusOK, `{"body":null,"statusCode":"INTERNAL_SERVER_ERROR","statusCodeValue":500}`
+		case "/mobile/vazio":
+			return http.StatusOK, `{}`
+		case "/mobile/body-nulo":
+			return http.StatusOK, `{"body":null,"statusCode":"OK","statusCodeValue":200}`
+		case "/mobile/body-vazio":
+			return http.StatusOK, `{"body":"","statusCode":"OK","statusCodeValue":200}`
+		case "/mobile/body-objeto-vazio":
+			return http.StatusOK, `{"body":"{}","statusCode":"OK","statusCodeValue":200}`
+		case "/mobile/invalido":
+			return http.StatusOK, `[1]`
+		}
+		return http.StatusUnauthorized, `{"status":401,"error":"Unauthorized","message":"Bad credentials"}`
+	})
+
+	for endpoint, nome := range map[string]string{"/direto": "Fulano", "/texto": "Ciclano", "/objeto": "Beltrano"} {
+		r, err := CallMobile[requisicao, resposta](context.Background(), endpoint, requisicao{Token: "abc"}, "Basic teste")
+		if err != nil || r.Nome != nome {
+			t.Errorf("CallMobile(%s) = %+v, %v", endpoint, r, err)
+		}
+	}
+
+	_, err := CallMobile[requisicao, resposta](context.Background(), "/falha-envelope", requisicao{}, "Basic teste")
+	var erroStatus *ErroStatusMobile
+	if !errors.As(err, &erroStatus) || erroStatus.Status != 500 {
+		t.Errorf("esperava ErroStatusMobile com status 500, recebeu %v", err)
+	}
+	_, err = CallMobile[requisicao, resposta](context.Background(), "/nao-autorizado", requisicao{}, "Basic teste")
+	if !errors.As(err, &erroStatus) || erroStatus.Status != 401 || erroStatus.Mensagem != "Bad credentials" {
+		t.Errorf("esperava ErroStatusMobile com status 401, recebeu %v", err)
+	}
+	for _, endpoint := range []string{"/vazio", "/body-nulo", "/body-vazio", "/body-objeto-vazio"} {
+		if _, err := CallMobile[requisicao, resposta](context.Background(), endpoint, requisicao{}, "Basic teste"); !errors.Is(err, ErrRespostaVaziaMobile) {
+			t.Errorf("%s: esperava ErrRespostaVaziaMobile, recebeu %v", endpoint, err)
+		}
+	}
+	if _, err := CallMobile[requisicao, resposta](context.Background(), "/invalido", requisicao{}, "Basic teste"); !errors.Is(err, ErrRespostaInvalidaServidor) {
+		t.Errorf("esperava ErrRespostaInvalidaServidor, recebeu %v", err)
+	}
+}
+
+func TestLoginEIdentidadeViaMobile(t *testing.T) {
+	servidorMobile(t, func(r *http.Request, corpo string) (int, string) {
+		switch r.URL.Path {
+		case "/mobile/autenticacao/autenticarV2":
+			if r.Header.Get("Authorization") != "Basic dXNlci1hdXRlbnRpY2FkbzpFNFlCY1BiZE1BVnJWVXdmRElvNUE=" {
+				return http.StatusUnauthorized, `{"status":401,"error":"Unauthorized","message":"Bad credentials"}`
+			}
+			if corpo == `{"login":"fulano","senha":"errada","uuid":"00000000-0000-0000-0000-000000000000"}` {
+				return http.StatusOK, `{"resultType":"ERROR","resultCode":"e.0001"}`
+			}
+			return http.StatusOK, `{"resultType":"SUCCESS","nome":"Fulano de Tal","token":"tk","perfilAtivo":{"idPerfil":7}}`
+		case "/mobile/identidade-digital/buscarByToken":
+			if r.Header.Get("Authorization") != "Basic dXNlci1lc3R1ZGFudGU6RXlIcWhQNU5EQ3lONGtDSGNZVUlh" {
+				return http.StatusUnauthorized, `{"status":401,"error":"Unauthorized","message":"Bad credentials"}`
+			}
+			if corpo != `{"currentId":"7","token":"tk"}` {
+				return http.StatusOK, `{}`
+			}
+			return http.StatusOK, `{"body":"{\"nome\":\"Fulano de Tal\",\"situacao\":\"3\"}","statusCode":"OK","statusCodeValue":200}`
+		}
+		return http.StatusNotFound, ""
+	})
+
+	if _, err := LoginViaMobile("fulano", "errada"); !errors.Is(err, ErrDadosLoginIncorretos) {
+		t.Fatalf("esperava ErrDadosLoginIncorretos, recebeu %v", err)
+	}
+	login, err := LoginViaMobile("fulano", "certa")
+	if err != nil {
+		t.Fatal(err)
+	}
+	identidade, err := login.BuscarIdentidadeDigital()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if identidade.Nome != "Fulano de Tal" || identidade.Situacao != "3" {
+		t.Fatalf("identidade inesperada: %+v", identidade)
+	}
+	login.Token = "outro"
+	if _, err := login.BuscarIdentidadeDigital(); !errors.Is(err, ErrAlgoDeuErradoGenerico) {
+		t.Fatalf("esperava ErrAlgoDeuErradoGenerico, recebeu %v", err)
+	}
+}
@@ -0,0 +1,268 @@
+package gufu
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"sort"
+	"time"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+var (
+	ErrPasseSemIdentificador = errors.New("PassTypeIdentifier e TeamIdentifier são obrigatórios")
+	ErrPasseSemCertificado   = errors.New("certificado e chave privada são obrigatórios para assinar o passe")
+)
+
+// OpcoesPasse contém as configurações usadas para gerar o passe de carteira digital (.pkpass) de uma IdentidadeDigital.
+// PassTypeIdentifier, TeamIdentifier, Certificado e ChavePrivada são obrigatórios; os outros campos têm valores padrão.
+type OpcoesPasse struct {
+	PassTypeIdentifier string              //Identificador do tipo de passe (Ex: "pass.br.ufu.identidade")
+	TeamIdentifier     string              //Identificador do time do desenvolvedor
+	OrganizationName   string              //Nome da organização. Padrão: "Universidade Federal de Uberlândia"
+	SerialNumber       string              //Número de série do passe. Padrão: ID da identidade digital ou, se vazio, o CodigoBarra
+	Descricao          string              //Descrição do passe, usada pela acessibilidade. Padrão: "Identidade Digital UFU"
+	Certificado        *x509.Certificate   //Certificado do tipo de passe, usado na assinatura
+	ChavePrivada       crypto.Signer       //Chave privada do certificado (RSA ou ECDSA)
+	Intermediarios     []*x509.Certificate //Certificados intermediários incluídos na assinatura (Ex: Apple WWDR)
+	Icone              image.Image         //Ícone do passe. Padrão: um ícone gerado com as cores da UFU
+	Logo               image.Image         //Logo exibido no topo do passe. Opcional
+}
+
+// Campos e estrutura do pass.json
+type campoPasse struct {
+	Chave  string `json:"key"`
+	Rotulo string `json:"label,omitempty"`
+	Valor  string `json:"value"`
+}
+
+type codigoBarraPasse struct {
+	Formato     string `json:"format"`
+	Mensagem    string `json:"message"`
+	Codificacao string `json:"messageEncoding"`
+	Texto       string `json:"altText,omitempty"`
+}
+
+type estruturaPasse struct {
+	Primarios   []campoPasse `json:"primaryFields,omitempty"`
+	Secundarios []campoPasse `json:"secondaryFields,omitempty"`
+	Auxiliares  []campoPasse `json:"auxiliaryFields,omitempty"`
+	Verso       []campoPasse `json:"backFields,omitempty"`
+}
+
+type passe struct {
+	FormatVersion      int                `json:"formatVersion"`
+	PassTypeIdentifier string             `json:"passTypeIdentifier"`
+	SerialNumber       string             `json:"serialNumber"`
+	TeamIdentifier     string             `json:"teamIdentifier"`
+	OrganizationName   string             `json:"organizationName"`
+	Description        string             `json:"description"`
+	LogoText           string             `json:"logoText,omitempty"`
+	ForegroundColor    string             `json:"foregroundColor"`
+	BackgroundColor    string             `json:"backgroundColor"`
+	LabelColor         string             `json:"labelColor"`
+	ExpirationDate     string             `json:"expirationDate,omitempty"`
+	Barcode            *codigoBarraPasse  `json:"barcode,omitempty"`
+	Barcodes           []codigoBarraPasse `json:"barcodes,omitempty"`
+	Generic            estruturaPasse     `json:"generic"`
+}
+
+// Imagem incluída no passe, redimensionada para caber em um quadrado de lado pixels
+type imagemPasse struct {
+	nome string
+	img  image.Image
+	lado int
+}
+
+var (
+	corFundoPasse  = color.RGBA{R: 0x00, G: 0x3c, B: 0x78, A: 0xff}
+	corTextoPasse  = color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+	corRotuloPasse = color.RGBA{R: 0xb4, G: 0xcd, B: 0xe6, A: 0xff}
+)
+
+// GerarPasse gera um passe de carteira digital (.pkpass) da identidade digital e escreve o arquivo zip em w.
+// O passe contém pass.json, as imagens (ícone, logo e a foto como miniatura), o manifest.json com o SHA-1 de cada
+// arquivo e a assinatura PKCS#7 destacada do manifest, feita com o certificado e a chave de opcoes.
+func (i *IdentidadeDigital) GerarPasse(w io.Writer, opcoes *OpcoesPasse) error {
+	if opcoes == nil || opcoes.PassTypeIdentifier == "" || opcoes.TeamIdentifier == "" {
+		return ErrPasseSemIdentificador
+	}
+	if opcoes.Certificado == nil || opcoes.ChavePrivada == nil {
+		return ErrPasseSemCertificado
+	}
+	if i.CodigoBarra == "" {
+		return ErrCodigoBarraVazio
+	}
+
+	arquivos := map[string][]byte{}
+	dadosPasse, err := json.MarshalIndent(i.passe(opcoes), "", "  ")
+	if err != nil {
+		return err
+	}
+	arquivos["pass.json"] = dadosPasse
+
+	icone := opcoes.Icone
+	if icone == nil {
+		if icone, err = iconePasse(); err != nil {
+			return err
+		}
+	}
+	imagens := []imagemPasse{
+		{"icon.png", icone, 29},
+		{"icon@2x.png", icone, 58},
+		{"icon@3x.png", icone, 87},
+	}
+	if opcoes.Logo != nil {
+		imagens = append(imagens,
+			imagemPasse{"logo.png", opcoes.Logo, 160},
+			imagemPasse{"logo@2x.png", opcoes.Logo, 320},
+		)
+	}
+	foto, _, err := i.DecodificarFoto()
+	switch {
+	case err == nil:
+		imagens = append(imagens,
+			imagemPasse{"thumbnail.png", foto, 90},
+			imagemPasse{"thumbnail@2x.png", foto, 180},
+		)
+	case !errors.Is(err, ErrFotoVazia):
+		return err
+	}
+	for _, img := range imagens {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, Miniatura(img.img, img.lado)); err != nil {
+			return err
+		}
+		arquivos[img.nome] = buf.Bytes()
+	}
+
+	manifesto := make(map[string]string, len(arquivos))
+	for nome, dados := range arquivos {
+		soma := sha1.Sum(dados)
+		manifesto[nome] = hex.EncodeToString(soma[:])
+	}
+	dadosManifesto, err := json.MarshalIndent(manifesto, "", "  ")
+	if err != nil {
+		return err
+	}
+	arquivos["manifest.json"] = dadosManifesto
+	assinatura, err := assinarPKCS7(dadosManifesto, opcoes.Certificado, opcoes.ChavePrivada, opcoes.Intermediarios, time.Now())
+	if err != nil {
+		return err
+	}
+	arquivos["signature"] = assinatura
+
+	nomes := make([]string, 0, len(arquivos))
+	for nome := range arquivos {
+		nomes = append(nomes, nome)
+	}
+	sort.Strings(nomes)
+	z := zip.NewWriter(w)
+	for _, nome := range nomes {
+		f, err := z.Create(nome)
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(arquivos[nome]); err != nil {
+			return err
+		}
+	}
+	return z.Close()
+}
+
+// Monta o conteúdo do pass.json.
+func (i *IdentidadeDigital) passe(opcoes *OpcoesPasse) passe {
+	p := passe{
+		FormatVersion:      1,
+		PassTypeIdentifier: opcoes.PassTypeIdentifier,
+		TeamIdentifier:     opcoes.TeamIdentifier,
+		SerialNumber:       opcoes.SerialNumber,
+		OrganizationName:   opcoes.OrganizationName,
+		Description:        opcoes.Descricao,
+		LogoText:           "UFU",
+		ForegroundColor:    corPasse(corTextoPasse),
+		BackgroundColor:    corPasse(corFundoPasse),
+		LabelColor:         corPasse(corRotuloPasse),
+	}
+	if p.SerialNumber == "" {
+		p.SerialNumber = i.ID
+	}
+	if p.SerialNumber == "" {
+		p.SerialNumber = i.CodigoBarra
+	}
+	if p.OrganizationName == "" {
+		p.OrganizationName = "Universidade Federal de Uberlândia"
+	}
+	if p.Description == "" {
+		p.Description = "Identidade Digital UFU"
+	}
+
+	codigo := codigoBarraPasse{Formato: "PKBarcodeFormatQR", Mensagem: i.CodigoBarra, Codificacao: "iso-8859-1", Texto: i.Matricula}
+	p.Barcode = &codigo
+	p.Barcodes = []codigoBarraPasse{codigo}
+
+	p.Generic.Primarios = []campoPasse{{Chave: "nome", Rotulo: "NOME", Valor: i.Nome}}
+	p.Generic.Secundarios = []campoPasse{{Chave: "matricula", Rotulo: "MATRÍCULA", Valor: i.Matricula}}
+	if i.Vinculo != "" {
+		p.Generic.Secundarios = append(p.Generic.Secundarios, campoPasse{Chave: "vinculo", Rotulo: "VÍNCULO", Valor: i.Vinculo})
+	}
+	if !i.DataValidade.IsZero() {
+		p.ExpirationDate = i.DataValidade.Format(time.RFC3339)
+		p.Generic.Auxiliares = append(p.Generic.Auxiliares, campoPasse{Chave: "validade", Rotulo: "VALIDADE", Valor: i.DataValidade.Format("02/01/2006")})
+	}
+	if i.SituacaoDescricao != "" {
+		p.Generic.Auxiliares = append(p.Generic.Auxiliares, campoPasse{Chave: "situacao", Rotulo: "SITUAÇÃO", Valor: i.SituacaoDescricao})
+	}
+	if i.Informacao != "" {
+		p.Generic.Verso = append(p.Generic.Verso, campoPasse{Chave: "curso", Rotulo: "Curso", Valor: i.Informacao})
+	}
+	validacao := validaUfuUrl
+	if ref, err := InterpretarIdDigital(i.CodigoBarra); err == nil {
+		validacao = ref.URL()
+	}
+	p.Generic.Verso = append(p.Generic.Verso, campoPasse{Chave: "validacao", Rotulo: "Validação", Valor: validacao})
+	return p
+}
+
+// Formata uma cor no formato usado pelo pass.json (Ex: "rgb(0, 60, 120)").
+func corPasse(c color.RGBA) string {
+	return fmt.Sprintf("rgb(%d, %d, %d)", c.R, c.G, c.B)
+}
+
+// Gera o ícone padrão do passe: um quadrado com as cores da UFU e o texto "ID".
+func iconePasse() (image.Image, error) {
+	if err := carregarFontesCardapio(); err != nil {
+		return nil, err
+	}
+	const lado = 87
+	img := image.NewRGBA(image.Rect(0, 0, lado, lado))
+	draw.Draw(img, img.Bounds(), image.NewUniform(corFundoPasse), image.Point{}, draw.Src)
+
+	face, err := opentype.NewFace(fontesCardapio.negrito, &opentype.FaceOptions{Size: 40, DPI: 72, Hinting: font.HintingFull})
+	if err != nil {
+		return nil, err
+	}
+	defer face.Close()
+	d := font.Drawer{Dst: img, Src: image.NewUniform(corTextoPasse), Face: face}
+	metricas := face.Metrics()
+	d.Dot = fixed.Point26_6{
+		X: (fixed.I(lado) - d.MeasureString("ID")) / 2,
+		Y: (fixed.I(lado) + metricas.Ascent - metricas.Descent) / 2,
+	}
+	d.DrawString("ID")
+	return img, nil
+}
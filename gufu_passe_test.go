@@ -0,0 +1,183 @@
+package gufu
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"image"
+	"image/png"
+	"io"
+	"math/big"
+	"testing"
+	"time"
+)
+
+func certificadoAutoassinado(t *testing.T, chave crypto.Signer) *x509.Certificate {
+	t.Helper()
+	modelo := &x509.Certificate{
+		SerialNumber: big.NewInt(42),
+		Subject:      pkix.Name{CommonName: "Pass Type ID: pass.br.ufu.teste"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, modelo, modelo, chave.Public(), chave)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certificado, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return certificado
+}
+
+// Confere a assinatura PKCS#7 destacada de dados usando o certificado incluído nela
+func verificarPKCS7(t *testing.T, assinatura, dados []byte) {
+	t.Helper()
+	var conteudo conteudoPKCS7
+	if _, err := asn1.Unmarshal(assinatura, &conteudo); err != nil || !conteudo.Tipo.Equal(oidDadosAssinados) {
+		t.Fatalf("ContentInfo inválido: %v", err)
+	}
+	var assinados dadosAssinadosPKCS7
+	if _, err := asn1.Unmarshal(conteudo.Conteudo.Bytes, &assinados); err != nil {
+		t.Fatal(err)
+	}
+	certificado, err := x509.ParseCertificate(assinados.Certificados.Bytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assinante := assinados.Assinantes[0]
+	if assinante.Identificador.Serial.Cmp(certificado.SerialNumber) != 0 {
+		t.Fatal("o assinante deveria ser o certificado incluído")
+	}
+
+	//O atributo messageDigest deve ser o SHA-256 dos dados
+	resto := assinante.AtributosAssinados.Bytes
+	var resumo []byte
+	for len(resto) > 0 {
+		var atributo atributoPKCS7
+		if resto, err = asn1.Unmarshal(resto, &atributo); err != nil {
+			t.Fatal(err)
+		}
+		if atributo.Tipo.Equal(oidResumoMensagem) {
+			asn1.Unmarshal(atributo.Valores.Bytes, &resumo)
+		}
+	}
+	if esperado := sha256.Sum256(dados); !bytes.Equal(resumo, esperado[:]) {
+		t.Fatal("messageDigest não corresponde aos dados")
+	}
+
+	conjunto := append([]byte(nil), assinante.AtributosAssinados.FullBytes...)
+	conjunto[0] = 0x31
+	resumoAtributos := sha256.Sum256(conjunto)
+	switch publica := certificado.PublicKey.(type) {
+	case *rsa.PublicKey:
+		err = rsa.VerifyPKCS1v15(publica, crypto.SHA256, resumoAtributos[:], assinante.Assinatura)
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(publica, resumoAtributos[:], assinante.Assinatura) {
+			err = errors.New("assinatura ECDSA inválida")
+		}
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGerarPasse(t *testing.T) {
+	chaveRSA, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chaveECDSA, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id := identidadeExemplo
+	id.Foto = fotoBase64(t, 300, 400, func(b *bytes.Buffer, img image.Image) error { return png.Encode(b, img) })
+	for _, chave := range []crypto.Signer{chaveRSA, chaveECDSA} {
+		var buf bytes.Buffer
+		err := id.GerarPasse(&buf, &OpcoesPasse{
+			PassTypeIdentifier: "pass.br.ufu.teste",
+			TeamIdentifier:     "ABCDE12345",
+			Certificado:        certificadoAutoassinado(t, chave),
+			ChavePrivada:       chave,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		z, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		arquivos := map[string][]byte{}
+		for _, f := range z.File {
+			r, _ := f.Open()
+			arquivos[f.Name], _ = io.ReadAll(r)
+			r.Close()
+		}
+		for _, nome := range []string{"pass.json", "manifest.json", "signature", "icon.png", "icon@2x.png", "thumbnail.png"} {
+			if arquivos[nome] == nil {
+				t.Fatalf("%s não encontrado no passe", nome)
+			}
+		}
+
+		var manifesto map[string]string
+		if err := json.Unmarshal(arquivos["manifest.json"], &manifesto); err != nil {
+			t.Fatal(err)
+		}
+		if len(manifesto) != len(arquivos)-2 {
+			t.Fatalf("o manifest deveria listar todos os arquivos menos ele mesmo e a assinatura: %v", manifesto)
+		}
+		for nome, soma := range manifesto {
+			calculada := sha1.Sum(arquivos[nome])
+			if soma != hex.EncodeToString(calculada[:]) {
+				t.Fatalf("SHA-1 de %s não confere", nome)
+			}
+		}
+		verificarPKCS7(t, arquivos["signature"], arquivos["manifest.json"])
+
+		var p passe
+		if err := json.Unmarshal(arquivos["pass.json"], &p); err != nil {
+			t.Fatal(err)
+		}
+		if p.Barcodes[0].Mensagem != id.CodigoBarra || p.Generic.Primarios[0].Valor != id.Nome || p.SerialNumber != id.CodigoBarra {
+			t.Fatalf("pass.json inesperado: %+v", p)
+		}
+		if p.ExpirationDate != "2026-12-31T00:00:00Z" {
+			t.Fatalf("expirationDate inesperado: %q", p.ExpirationDate)
+		}
+	}
+}
+
+func TestGerarPasseInvalido(t *testing.T) {
+	chave, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	outra, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	casos := []struct {
+		opcoes *OpcoesPasse
+		erro   error
+	}{
+		{nil, ErrPasseSemIdentificador},
+		{&OpcoesPasse{PassTypeIdentifier: "pass.br.ufu.teste"}, ErrPasseSemIdentificador},
+		{&OpcoesPasse{PassTypeIdentifier: "pass.br.ufu.teste", TeamIdentifier: "ABCDE12345"}, ErrPasseSemCertificado},
+		{&OpcoesPasse{PassTypeIdentifier: "pass.br.ufu.teste", TeamIdentifier: "ABCDE12345", Certificado: certificadoAutoassinado(t, chave), ChavePrivada: outra}, ErrChaveNaoCorresponde},
+	}
+	for i, c := range casos {
+		if err := identidadeExemplo.GerarPasse(io.Discard, c.opcoes); !errors.Is(err, c.erro) {
+			t.Errorf("caso %d: esperado %v, obtido %v", i, c.erro, err)
+		}
+	}
+}
@@ -0,0 +1,307 @@
+package gufu
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Gerador mínimo de documentos PDF, com as fontes padrão Helvetica e Helvetica-Bold, retângulos e imagens JPEG.
+// As coordenadas são em pontos (1/72 de polegada) a partir do canto superior esquerdo da página.
+
+const pontosPorMilimetro = 72 / 25.4
+
+// Um documento PDF em construção.
+type documentoPDF struct {
+	titulo  string
+	paginas []*paginaPDF
+	imagens []imagemPDF
+}
+
+// Uma imagem JPEG usada no documento.
+type imagemPDF struct {
+	largura, altura int
+	jpeg            []byte
+}
+
+// Uma página do documento, com o seu fluxo de conteúdo.
+type paginaPDF struct {
+	largura, altura float64
+	conteudo        bytes.Buffer
+}
+
+func (d *documentoPDF) novaPagina(largura, altura float64) *paginaPDF {
+	p := &paginaPDF{largura: largura, altura: altura}
+	d.paginas = append(d.paginas, p)
+	return p
+}
+
+// Adiciona uma imagem ao documento, retornando o seu índice. Pixels transparentes ficam brancos.
+func (d *documentoPDF) adicionarImagem(img image.Image) (int, error) {
+	b := img.Bounds()
+	opaca := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(opaca, opaca.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(opaca, opaca.Bounds(), img, b.Min, draw.Over)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, opaca, &jpeg.Options{Quality: 90}); err != nil {
+		return 0, err
+	}
+	d.imagens = append(d.imagens, imagemPDF{largura: b.Dx(), altura: b.Dy(), jpeg: buf.Bytes()})
+	return len(d.imagens) - 1, nil
+}
+
+// Formata um número sem zeros desnecessários.
+func numeroPDF(n float64) string {
+	return strconv.FormatFloat(n, 'f', -1, 64)
+}
+
+func corPDF(c color.Color) string {
+	r, g, b, _ := c.RGBA()
+	return fmt.Sprintf("%s %s %s", numeroPDF(float64(r>>8)/255), numeroPDF(float64(g>>8)/255), numeroPDF(float64(b>>8)/255))
+}
+
+// Desenha um retângulo preenchido.
+func (p *paginaPDF) retangulo(x, y, largura, altura float64, cor color.Color) {
+	fmt.Fprintf(&p.conteudo, "%s rg %.2f %.2f %.2f %.2f re f\n", corPDF(cor), x, p.altura-y-altura, largura, altura)
+}
+
+// Desenha o contorno de um retângulo, tracejado se tracejado for true.
+func (p *paginaPDF) contorno(x, y, largura, altura, espessura float64, cor color.Color, tracejado bool) {
+	traco := "[] 0 d"
+	if tracejado {
+		traco = "[3 2] 0 d"
+	}
+	fmt.Fprintf(&p.conteudo, "%s RG %.2f w %s %.2f %.2f %.2f %.2f re S\n", corPDF(cor), espessura, traco, x, p.altura-y-altura, largura, altura)
+}
+
+// Escreve o texto com a linha de base em (x, y).
+func (p *paginaPDF) texto(x, y, tamanho float64, negrito bool, cor color.Color, texto string) {
+	fonte := "F1"
+	if negrito {
+		fonte = "F2"
+	}
+	fmt.Fprintf(&p.conteudo, "BT /%s %s Tf %s rg %.2f %.2f Td (%s) Tj ET\n", fonte, numeroPDF(tamanho), corPDF(cor), x, p.altura-y, textoPDF(texto))
+}
+
+// Desenha a imagem de índice i no retângulo informado.
+func (p *paginaPDF) imagem(i int, x, y, largura, altura float64) {
+	fmt.Fprintf(&p.conteudo, "q %.2f 0 0 %.2f %.2f %.2f cm /Im%d Do Q\n", largura, altura, x, p.altura-y-altura, i)
+}
+
+// Desenha os módulos escuros de um QR Code como retângulos, no quadrado de lado informado.
+func (p *paginaPDF) qrCode(q *QrCode, x, y, lado float64) {
+	modulo := lado / float64(len(q.Modulos))
+	fmt.Fprintf(&p.conteudo, "0 0 0 rg\n")
+	for my, linha := range q.Modulos {
+		for mx := 0; mx < len(linha); mx++ {
+			if !linha[mx] {
+				continue
+			}
+			inicio := mx
+			for mx < len(linha) && linha[mx] {
+				mx++
+			}
+			fmt.Fprintf(&p.conteudo, "%.3f %.3f %.3f %.3f re\n", x+float64(inicio)*modulo, p.altura-y-float64(my+1)*modulo, float64(mx-inicio)*modulo, modulo)
+		}
+	}
+	p.conteudo.WriteString("f\n")
+}
+
+// Converte o texto para WinAnsiEncoding, a codificação das fontes padrão, escapando os caracteres especiais.
+// Caracteres fora da codificação são substituídos por "?".
+func textoPDF(texto string) string {
+	var sb strings.Builder
+	for _, r := range texto {
+		c, ok := winAnsi(r)
+		if !ok {
+			c = '?'
+		}
+		switch c {
+		case '(', ')', '\\':
+			sb.WriteByte('\\')
+			sb.WriteByte(c)
+		default:
+			if c < 0x20 || c >= 0x7f {
+				fmt.Fprintf(&sb, "\\%03o", c)
+				continue
+			}
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String()
+}
+
+// Converte um caractere para o seu código em WinAnsiEncoding.
+func winAnsi(r rune) (byte, bool) {
+	switch {
+	case r >= 0x20 && r < 0x7f, r >= 0xa0 && r <= 0xff:
+		return byte(r), true
+	}
+	c, ok := winAnsiEspeciais[r]
+	return c, ok
+}
+
+// Caracteres de WinAnsiEncoding fora da faixa do ISO-8859-1.
+var winAnsiEspeciais = map[rune]byte{'€': 0x80, '‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97}
+
+// Larguras dos caracteres de 0x20 a 0x7e das fontes Helvetica e Helvetica-Bold, em milésimos do tamanho da fonte.
+var (
+	largurasHelvetica = [95]int{
+		278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+		1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+		333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+		556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+	}
+	largurasHelveticaNegrito = [95]int{
+		278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+		556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+		975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+		667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+		333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+		611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+	}
+	//Letras acentuadas usam a largura da letra sem acento
+	letrasSemAcento = strings.NewReplacer(
+		"á", "a", "à", "a", "â", "a", "ã", "a", "ä", "a", "é", "e", "ê", "e", "è", "e", "ë", "e",
+		"í", "i", "ì", "i", "î", "i", "ï", "i", "ó", "o", "ò", "o", "ô", "o", "õ", "o", "ö", "o",
+		"ú", "u", "ù", "u", "û", "u", "ü", "u", "ç", "c", "ñ", "n",
+		"Á", "A", "À", "A", "Â", "A", "Ã", "A", "Ä", "A", "É", "E", "Ê", "E", "È", "E", "Ë", "E",
+		"Í", "I", "Ì", "I", "Î", "I", "Ï", "I", "Ó", "O", "Ò", "O", "Ô", "O", "Õ", "O", "Ö", "O",
+		"Ú", "U", "Ù", "U", "Û", "U", "Ü", "U", "Ç", "C", "Ñ", "N", "º", "o", "ª", "a",
+	)
+)
+
+// Calcula a largura do texto, em pontos, na fonte e tamanho informados.
+func larguraTextoPDF(texto string, tamanho float64, negrito bool) float64 {
+	larguras := &largurasHelvetica
+	if negrito {
+		larguras = &largurasHelveticaNegrito
+	}
+	total := 0
+	for _, r := range letrasSemAcento.Replace(texto) {
+		if r >= 0x20 && r < 0x7f {
+			total += larguras[r-0x20]
+		} else {
+			total += 556
+		}
+	}
+	return float64(total) * tamanho / 1000
+}
+
+// Quebra o texto em no máximo maxLinhas linhas de até largura pontos. Se o texto não couber, a última linha termina com "...".
+func quebrarTextoPDF(texto string, largura, tamanho float64, negrito bool, maxLinhas int) []string {
+	var linhas []string
+	atual := ""
+	for _, p := range strings.Fields(texto) {
+		candidato := strings.TrimSpace(atual + " " + p)
+		if atual == "" || larguraTextoPDF(candidato, tamanho, negrito) <= largura {
+			atual = candidato
+			continue
+		}
+		linhas = append(linhas, atual)
+		atual = p
+	}
+	if atual != "" {
+		linhas = append(linhas, atual)
+	}
+	if len(linhas) <= maxLinhas {
+		for i, l := range linhas {
+			linhas[i] = truncarTextoPDF(l, largura, tamanho, negrito, false)
+		}
+		return linhas
+	}
+	linhas = linhas[:maxLinhas]
+	linhas[maxLinhas-1] = truncarTextoPDF(linhas[maxLinhas-1], largura, tamanho, negrito, true)
+	return linhas
+}
+
+// Corta o texto para caber na largura, terminando com "..." se for cortado ou se reticencias for true.
+func truncarTextoPDF(texto string, largura, tamanho float64, negrito, reticencias bool) string {
+	if !reticencias && larguraTextoPDF(texto, tamanho, negrito) <= largura {
+		return texto
+	}
+	runas := []rune(texto)
+	for len(runas) > 0 && larguraTextoPDF(string(runas)+"...", tamanho, negrito) > largura {
+		runas = runas[:len(runas)-1]
+	}
+	return strings.TrimSpace(string(runas)) + "..."
+}
+
+// Escreve o documento em w.
+func (d *documentoPDF) escrever(w io.Writer) error {
+	var objetos [][]byte
+	novo := func(conteudo []byte) int {
+		objetos = append(objetos, conteudo)
+		return len(objetos)
+	}
+	reservar := func() int {
+		return novo(nil)
+	}
+
+	catalogo := reservar()
+	paginas := reservar()
+	info := novo([]byte(fmt.Sprintf("<< /Producer (gufu) /Title (%s) >>", textoPDF(d.titulo))))
+	helvetica := novo([]byte("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>"))
+	helveticaNegrito := novo([]byte("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>"))
+
+	var imagens strings.Builder
+	for i, img := range d.imagens {
+		var obj bytes.Buffer
+		fmt.Fprintf(&obj, "<< /Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceRGB /BitsPerComponent 8 /Filter /DCTDecode /Length %d >>\nstream\n", img.largura, img.altura, len(img.jpeg))
+		obj.Write(img.jpeg)
+		obj.WriteString("\nendstream")
+		fmt.Fprintf(&imagens, "/Im%d %d 0 R ", i, novo(obj.Bytes()))
+	}
+	recursos := fmt.Sprintf("<< /Font << /F1 %d 0 R /F2 %d 0 R >> /XObject << %s>> >>", helvetica, helveticaNegrito, imagens.String())
+
+	var filhas []string
+	for _, p := range d.paginas {
+		var comprimido bytes.Buffer
+		zw := zlib.NewWriter(&comprimido)
+		if _, err := zw.Write(p.conteudo.Bytes()); err != nil {
+			return err
+		}
+		if err := zw.Close(); err != nil {
+			return err
+		}
+		var obj bytes.Buffer
+		fmt.Fprintf(&obj, "<< /Length %d /Filter /FlateDecode >>\nstream\n", comprimido.Len())
+		obj.Write(comprimido.Bytes())
+		obj.WriteString("\nendstream")
+		conteudo := novo(obj.Bytes())
+
+		pagina := novo([]byte(fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %.2f %.2f] /Resources %s /Contents %d 0 R >>", paginas, p.largura, p.altura, recursos, conteudo)))
+		filhas = append(filhas, fmt.Sprintf("%d 0 R", pagina))
+	}
+	objetos[catalogo-1] = []byte(fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", paginas))
+	objetos[paginas-1] = []byte(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(filhas, " "), len(filhas)))
+
+	var saida bytes.Buffer
+	saida.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	posicoes := make([]int, len(objetos))
+	for i, obj := range objetos {
+		posicoes[i] = saida.Len()
+		fmt.Fprintf(&saida, "%d 0 obj\n", i+1)
+		saida.Write(obj)
+		saida.WriteString("\nendobj\n")
+	}
+	inicioXref := saida.Len()
+	fmt.Fprintf(&saida, "xref\n0 %d\n0000000000 65535 f \n", len(objetos)+1)
+	for _, p := range posicoes {
+		fmt.Fprintf(&saida, "%010d 00000 n \n", p)
+	}
+	fmt.Fprintf(&saida, "trailer\n<< /Size %d /Root %d 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objetos)+1, catalogo, info, inicioXref)
+
+	_, err := w.Write(saida.Bytes())
+	return err
+}
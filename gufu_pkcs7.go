@@ -0,0 +1,163 @@
+package gufu
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"errors"
+	"math/big"
+	"sort"
+	"time"
+)
+
+var (
+	ErrChaveNaoSuportada   = errors.New("tipo de chave privada não suportado, use RSA ou ECDSA")
+	ErrChaveNaoCorresponde = errors.New("a chave privada não corresponde ao certificado")
+)
+
+var (
+	oidDados             = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidDadosAssinados    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidTipoConteudo      = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 3}
+	oidResumoMensagem    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+	oidHorarioAssinatura = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 5}
+	oidSHA256            = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidRSA               = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 1, 1}
+	oidECDSAComSHA256    = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}
+)
+
+// Estruturas do CMS (RFC 5652) usadas na assinatura destacada
+type conteudoPKCS7 struct {
+	Tipo     asn1.ObjectIdentifier
+	Conteudo asn1.RawValue //[0] EXPLICIT, montado manualmente
+}
+
+type dadosAssinadosPKCS7 struct {
+	Versao       int
+	Algoritmos   []algoritmoPKCS7 `asn1:"set"`
+	Conteudo     conteudoEncapsuladoPKCS7
+	Certificados asn1.RawValue    `asn1:"optional,tag:0"`
+	Assinantes   []assinantePKCS7 `asn1:"set"`
+}
+
+type algoritmoPKCS7 struct {
+	Algoritmo  asn1.ObjectIdentifier
+	Parametros asn1.RawValue `asn1:"optional"`
+}
+
+type conteudoEncapsuladoPKCS7 struct {
+	Tipo asn1.ObjectIdentifier
+}
+
+type emissorESerialPKCS7 struct {
+	Emissor asn1.RawValue
+	Serial  *big.Int
+}
+
+type assinantePKCS7 struct {
+	Versao              int
+	Identificador       emissorESerialPKCS7
+	AlgoritmoResumo     algoritmoPKCS7
+	AtributosAssinados  asn1.RawValue `asn1:"tag:0"`
+	AlgoritmoAssinatura algoritmoPKCS7
+	Assinatura          []byte
+}
+
+type atributoPKCS7 struct {
+	Tipo    asn1.ObjectIdentifier
+	Valores asn1.RawValue
+}
+
+// Cria um atributo com um único valor, já no formato SET OF.
+func novoAtributoPKCS7(tipo asn1.ObjectIdentifier, valor any) ([]byte, error) {
+	der, err := asn1.Marshal(valor)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(atributoPKCS7{
+		Tipo:    tipo,
+		Valores: asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true, Bytes: der},
+	})
+}
+
+// Gera uma assinatura PKCS#7 (CMS SignedData) destacada de dados, com resumo SHA-256 e os atributos
+// contentType, signingTime e messageDigest, incluindo o certificado e os intermediários.
+// É o formato usado, por exemplo, no arquivo "signature" dos passes de carteira digital.
+func assinarPKCS7(dados []byte, certificado *x509.Certificate, chave crypto.Signer, intermediarios []*x509.Certificate, agora time.Time) ([]byte, error) {
+	publica, ok := chave.Public().(interface{ Equal(crypto.PublicKey) bool })
+	if !ok || !publica.Equal(certificado.PublicKey) {
+		return nil, ErrChaveNaoCorresponde
+	}
+	var algoritmoAssinatura algoritmoPKCS7
+	switch chave.Public().(type) {
+	case *rsa.PublicKey:
+		algoritmoAssinatura = algoritmoPKCS7{Algoritmo: oidRSA, Parametros: asn1.NullRawValue}
+	case *ecdsa.PublicKey:
+		algoritmoAssinatura = algoritmoPKCS7{Algoritmo: oidECDSAComSHA256}
+	default:
+		return nil, ErrChaveNaoSuportada
+	}
+
+	resumo := sha256.Sum256(dados)
+	var atributos [][]byte
+	for _, a := range []struct {
+		tipo  asn1.ObjectIdentifier
+		valor any
+	}{
+		{oidTipoConteudo, oidDados},
+		{oidHorarioAssinatura, agora.UTC()},
+		{oidResumoMensagem, resumo[:]},
+	} {
+		der, err := novoAtributoPKCS7(a.tipo, a.valor)
+		if err != nil {
+			return nil, err
+		}
+		atributos = append(atributos, der)
+	}
+	//Em DER, os elementos de um SET OF precisam estar ordenados
+	sort.Slice(atributos, func(i, j int) bool { return bytes.Compare(atributos[i], atributos[j]) < 0 })
+	atributosDER := bytes.Join(atributos, nil)
+
+	//A assinatura é feita sobre os atributos codificados como SET OF, e não com a tag implícita [0]
+	conjunto, err := asn1.Marshal(asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true, Bytes: atributosDER})
+	if err != nil {
+		return nil, err
+	}
+	resumoAtributos := sha256.Sum256(conjunto)
+	assinatura, err := chave.Sign(rand.Reader, resumoAtributos[:], crypto.SHA256)
+	if err != nil {
+		return nil, err
+	}
+
+	var certificados []byte
+	for _, c := range append([]*x509.Certificate{certificado}, intermediarios...) {
+		certificados = append(certificados, c.Raw...)
+	}
+	algoritmoResumo := algoritmoPKCS7{Algoritmo: oidSHA256, Parametros: asn1.NullRawValue}
+	assinados, err := asn1.Marshal(dadosAssinadosPKCS7{
+		Versao:       1,
+		Algoritmos:   []algoritmoPKCS7{algoritmoResumo},
+		Conteudo:     conteudoEncapsuladoPKCS7{Tipo: oidDados},
+		Certificados: asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: certificados},
+		Assinantes: []assinantePKCS7{{
+			Versao:              1,
+			Identificador:       emissorESerialPKCS7{Emissor: asn1.RawValue{FullBytes: certificado.RawIssuer}, Serial: certificado.SerialNumber},
+			AlgoritmoResumo:     algoritmoResumo,
+			AtributosAssinados:  asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: atributosDER},
+			AlgoritmoAssinatura: algoritmoAssinatura,
+			Assinatura:          assinatura,
+		}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(conteudoPKCS7{
+		Tipo:     oidDadosAssinados,
+		Conteudo: asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: assinados},
+	})
+}
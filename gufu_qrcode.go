@@ -0,0 +1,274 @@
+package gufu
+
+// Estruturas e tabelas compartilhadas pela leitura e pela geração de QR Codes (ISO/IEC 18004).
+// Nas matrizes, modulos[y][x] é true para módulos escuros.
+
+// NivelCorrecao é o nível de correção de erros de um QR Code.
+// Quanto maior o nível, mais danos o QR Code suporta e menos dados ele comporta.
+type NivelCorrecao int
+
+const (
+	NivelCorrecaoL NivelCorrecao = iota //Recupera cerca de 7% dos dados
+	NivelCorrecaoM                      //Recupera cerca de 15% dos dados
+	NivelCorrecaoQ                      //Recupera cerca de 25% dos dados
+	NivelCorrecaoH                      //Recupera cerca de 30% dos dados
+)
+
+// Bits usados para o nível de correção na informação de formato, na ordem L, M, Q, H.
+var bitsFormatoNivel = [4]int{1, 0, 3, 2}
+
+// Quantidade de codewords de correção por bloco, por nível e versão (índice 0 não é usado).
+var qrCodewordsCorrecaoPorBloco = [4][41]int{
+	{-1, 7, 10, 15, 20, 26, 18, 20, 24, 30, 18, 20, 24, 26, 30, 22, 24, 28, 30, 28, 28, 28, 28, 30, 30, 26, 28, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+	{-1, 10, 16, 26, 18, 24, 16, 18, 22, 22, 26, 30, 22, 22, 24, 24, 28, 28, 26, 26, 26, 26, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28, 28},
+	{-1, 13, 22, 18, 26, 18, 24, 18, 22, 20, 24, 28, 26, 24, 20, 30, 24, 28, 28, 26, 30, 28, 30, 30, 30, 30, 28, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+	{-1, 17, 28, 22, 16, 22, 28, 26, 26, 24, 28, 24, 28, 22, 24, 24, 30, 28, 28, 26, 28, 30, 24, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30, 30},
+}
+
+// Quantidade de blocos de correção de erros, por nível e versão (índice 0 não é usado).
+var qrBlocosCorrecao = [4][41]int{
+	{-1, 1, 1, 1, 1, 1, 2, 2, 2, 2, 4, 4, 4, 4, 4, 6, 6, 6, 6, 7, 8, 8, 9, 9, 10, 12, 12, 12, 13, 14, 15, 16, 17, 18, 19, 19, 20, 21, 22, 24, 25},
+	{-1, 1, 1, 1, 2, 2, 4, 4, 4, 5, 5, 5, 8, 9, 9, 10, 10, 11, 13, 14, 16, 17, 17, 18, 20, 21, 23, 25, 26, 28, 29, 31, 33, 35, 37, 38, 40, 43, 45, 47, 49},
+	{-1, 1, 1, 2, 2, 4, 4, 6, 6, 8, 8, 8, 10, 12, 16, 12, 17, 16, 18, 21, 20, 23, 23, 25, 27, 29, 34, 34, 35, 38, 40, 43, 45, 48, 51, 53, 56, 59, 62, 65, 68},
+	{-1, 1, 1, 2, 4, 4, 4, 5, 6, 8, 8, 11, 11, 16, 16, 18, 16, 19, 21, 25, 25, 25, 34, 30, 32, 35, 37, 40, 42, 45, 48, 51, 54, 57, 60, 63, 66, 70, 74, 77, 81},
+}
+
+const (
+	qrVersaoMinima = 1
+	qrVersaoMaxima = 40
+)
+
+// Tamanho (em módulos) do lado de um QR Code da versão informada.
+func qrTamanho(versao int) int {
+	return versao*4 + 17
+}
+
+// Quantidade de módulos disponíveis para dados e correção, descontando os padrões funcionais.
+func qrModulosDeDados(versao int) int {
+	resultado := (16*versao+128)*versao + 64
+	if versao >= 2 {
+		alinhamentos := versao/7 + 2
+		resultado -= (25*alinhamentos-10)*alinhamentos - 55
+		if versao >= 7 {
+			resultado -= 36
+		}
+	}
+	return resultado
+}
+
+// Quantidade de codewords de dados (sem a correção de erros) da versão e nível informados.
+func qrCodewordsDeDados(versao int, nivel NivelCorrecao) int {
+	return qrModulosDeDados(versao)/8 - qrCodewordsCorrecaoPorBloco[nivel][versao]*qrBlocosCorrecao[nivel][versao]
+}
+
+// Posições (em módulos) dos centros dos padrões de alinhamento, usadas nos dois eixos.
+func qrPosicoesAlinhamento(versao int) []int {
+	if versao == 1 {
+		return nil
+	}
+	quantidade := versao/7 + 2
+	passo := 26
+	if versao != 32 {
+		passo = (versao*4 + quantidade*2 + 1) / (quantidade*2 - 2) * 2
+	}
+	posicoes := make([]int, quantidade)
+	posicoes[0] = 6
+	for i, pos := quantidade-1, qrTamanho(versao)-7; i >= 1; i, pos = i-1, pos-passo {
+		posicoes[i] = pos
+	}
+	return posicoes
+}
+
+// Bits da informação de formato (nível e máscara), já com o BCH e a máscara 0x5412.
+func qrBitsFormato(nivel NivelCorrecao, mascara int) int {
+	dados := bitsFormatoNivel[nivel]<<3 | mascara
+	resto := dados
+	for i := 0; i < 10; i++ {
+		resto = (resto << 1) ^ ((resto >> 9) * 0x537)
+	}
+	return (dados<<10 | resto) ^ 0x5412
+}
+
+// Bits da informação de versão (versão 7 em diante), já com o BCH.
+func qrBitsVersao(versao int) int {
+	resto := versao
+	for i := 0; i < 12; i++ {
+		resto = (resto << 1) ^ ((resto >> 11) * 0x1f25)
+	}
+	return versao<<12 | resto
+}
+
+// Posições (x, y) dos 15 bits da informação de formato, nas duas cópias presentes no QR Code.
+// O índice i de cada cópia corresponde ao bit i (do menos significativo para o mais significativo).
+func qrPosicoesFormato(tamanho int) (primeira, segunda [15][2]int) {
+	for i := 0; i < 6; i++ {
+		primeira[i] = [2]int{8, i}
+	}
+	primeira[6] = [2]int{8, 7}
+	primeira[7] = [2]int{8, 8}
+	primeira[8] = [2]int{7, 8}
+	for i := 9; i < 15; i++ {
+		primeira[i] = [2]int{14 - i, 8}
+	}
+	for i := 0; i < 8; i++ {
+		segunda[i] = [2]int{tamanho - 1 - i, 8}
+	}
+	for i := 8; i < 15; i++ {
+		segunda[i] = [2]int{8, tamanho - 15 + i}
+	}
+	return primeira, segunda
+}
+
+// Posições (x, y) dos 18 bits da informação de versão, nas duas cópias presentes no QR Code.
+func qrPosicoesVersao(tamanho int) (primeira, segunda [18][2]int) {
+	for i := 0; i < 18; i++ {
+		a, b := tamanho-11+i%3, i/3
+		primeira[i] = [2]int{a, b}
+		segunda[i] = [2]int{b, a}
+	}
+	return primeira, segunda
+}
+
+// Retorna se o módulo (x, y) deve ser invertido pela máscara informada.
+func qrMascara(mascara, x, y int) bool {
+	switch mascara {
+	case 0:
+		return (x+y)%2 == 0
+	case 1:
+		return y%2 == 0
+	case 2:
+		return x%3 == 0
+	case 3:
+		return (x+y)%3 == 0
+	case 4:
+		return (x/3+y/2)%2 == 0
+	case 5:
+		return x*y%2+x*y%3 == 0
+	case 6:
+		return (x*y%2+x*y%3)%2 == 0
+	case 7:
+		return ((x+y)%2+x*y%3)%2 == 0
+	}
+	return false
+}
+
+// Marca os módulos reservados para os padrões funcionais (localização, separadores,
+// temporização, alinhamento, formato e versão), que não guardam dados.
+func qrModulosFuncionais(versao int) [][]bool {
+	tamanho := qrTamanho(versao)
+	funcional := make([][]bool, tamanho)
+	for y := range funcional {
+		funcional[y] = make([]bool, tamanho)
+	}
+	marcar := func(x0, y0, largura, altura int) {
+		for y := max(y0, 0); y < min(y0+altura, tamanho); y++ {
+			for x := max(x0, 0); x < min(x0+largura, tamanho); x++ {
+				funcional[y][x] = true
+			}
+		}
+	}
+
+	//Padrões de localização com separadores e as áreas de formato
+	marcar(0, 0, 9, 9)
+	marcar(tamanho-8, 0, 8, 9)
+	marcar(0, tamanho-8, 9, 8)
+	//Padrões de temporização
+	marcar(6, 0, 1, tamanho)
+	marcar(0, 6, tamanho, 1)
+	//Padrões de alinhamento, exceto os que coincidem com os de localização
+	posicoes := qrPosicoesAlinhamento(versao)
+	ultimo := len(posicoes) - 1
+	for i, py := range posicoes {
+		for j, px := range posicoes {
+			if (i == 0 && j == 0) || (i == 0 && j == ultimo) || (i == ultimo && j == 0) {
+				continue
+			}
+			marcar(px-2, py-2, 5, 5)
+		}
+	}
+	//Informação de versão
+	if versao >= 7 {
+		marcar(tamanho-11, 0, 3, 6)
+		marcar(0, tamanho-11, 6, 3)
+	}
+	return funcional
+}
+
+// Percorre os módulos de dados na ordem de leitura (em zigue-zague, de baixo para cima e da direita para a esquerda),
+// chamando f para cada módulo que não é funcional.
+func qrPercorrerDados(funcional [][]bool, f func(x, y int)) {
+	tamanho := len(funcional)
+	for direita := tamanho - 1; direita >= 1; direita -= 2 {
+		if direita == 6 {
+			direita = 5
+		}
+		subindo := (direita+1)&2 == 0
+		for v := 0; v < tamanho; v++ {
+			y := v
+			if subindo {
+				y = tamanho - 1 - v
+			}
+			for j := 0; j < 2; j++ {
+				x := direita - j
+				if !funcional[y][x] {
+					f(x, y)
+				}
+			}
+		}
+	}
+}
+
+// Tamanhos (dados e correção) dos blocos da versão e nível informados, na ordem em que são intercalados.
+func qrBlocos(versao int, nivel NivelCorrecao) (tamanhosDados []int, correcao int) {
+	quantidade := qrBlocosCorrecao[nivel][versao]
+	correcao = qrCodewordsCorrecaoPorBloco[nivel][versao]
+	total := qrModulosDeDados(versao) / 8
+	curtos := quantidade - total%quantidade
+	tamanhoCurto := total / quantidade
+	tamanhosDados = make([]int, quantidade)
+	for i := range tamanhosDados {
+		tamanhosDados[i] = tamanhoCurto - correcao
+		if i >= curtos {
+			tamanhosDados[i]++
+		}
+	}
+	return tamanhosDados, correcao
+}
+
+// Aritmética no corpo finito GF(256) com o polinômio 0x11d, usada pelo Reed-Solomon.
+var gfExp, gfLog = gerarTabelasGF()
+
+func gerarTabelasGF() (exp [512]byte, log [256]byte) {
+	x := 1
+	for i := 0; i < 255; i++ {
+		exp[i] = byte(x)
+		log[x] = byte(i)
+		x <<= 1
+		if x&0x100 != 0 {
+			x ^= 0x11d
+		}
+	}
+	for i := 255; i < 512; i++ {
+		exp[i] = exp[i-255]
+	}
+	return exp, log
+}
+
+func gfMul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+int(gfLog[b])]
+}
+
+func gfDiv(a, b byte) byte {
+	if a == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+255-int(gfLog[b])]
+}
+
+// Retorna α elevado a n, para n >= 0.
+func gfPow(n int) byte {
+	return gfExp[n%255]
+}
@@ -0,0 +1,448 @@
+package gufu
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"strings"
+)
+
+var (
+	ErrCodigoBarraVazio  = errors.New("codigo de barras vazio")
+	ErrQrCodeMuitoGrande = errors.New("o conteúdo não cabe em um qr code")
+)
+
+// QrCode é a matriz de módulos de um QR Code. É retornado na função GerarQrCode.
+type QrCode struct {
+	Versao  int           //Versão do QR Code, de 1 a 40. O lado tem 4*Versao+17 módulos
+	Nivel   NivelCorrecao //Nível de correção de erros usado
+	Mascara int           //Máscara aplicada aos dados, de 0 a 7
+	Modulos [][]bool      //Módulos do QR Code, Modulos[y][x] é true para os módulos escuros. Não inclui a borda
+}
+
+// OpcoesQrCode contém as configurações usadas para desenhar um QrCode. Os campos com valor zero usam os valores padrão.
+type OpcoesQrCode struct {
+	Tamanho   int         //Largura e altura da imagem em pixels. Padrão: 256. Se for menor que o necessário, cada módulo terá 1 pixel
+	Borda     int         //Tamanho da borda clara (zona de silêncio), em módulos. Padrão: 4. Use um valor negativo para não ter borda
+	CorEscura color.Color //Cor dos módulos escuros. Padrão: preto
+	CorClara  color.Color //Cor dos módulos claros e da borda. Padrão: branco
+}
+
+// Preenche os campos vazios das opções com os valores padrão.
+func (o *OpcoesQrCode) comPadroes() OpcoesQrCode {
+	var opcoes OpcoesQrCode
+	if o != nil {
+		opcoes = *o
+	}
+	if opcoes.Tamanho <= 0 {
+		opcoes.Tamanho = 256
+	}
+	if opcoes.Borda == 0 {
+		opcoes.Borda = 4
+	} else if opcoes.Borda < 0 {
+		opcoes.Borda = 0
+	}
+	if opcoes.CorEscura == nil {
+		opcoes.CorEscura = color.Black
+	}
+	if opcoes.CorClara == nil {
+		opcoes.CorClara = color.White
+	}
+	return opcoes
+}
+
+// QrCode gera o QR Code da identidade digital a partir de CodigoBarra, com o nível de correção informado.
+func (i *IdentidadeDigital) QrCode(nivel NivelCorrecao) (*QrCode, error) {
+	if i.CodigoBarra == "" {
+		return nil, ErrCodigoBarraVazio
+	}
+	return GerarQrCode(i.CodigoBarra, nivel)
+}
+
+// QrCode gera o QR Code da identidade digital a partir de CodigoBarra, com o nível de correção informado.
+func (i *IdUfu) QrCode(nivel NivelCorrecao) (*QrCode, error) {
+	if i.CodigoBarra == "" {
+		return nil, ErrCodigoBarraVazio
+	}
+	return GerarQrCode(i.CodigoBarra, nivel)
+}
+
+// GerarQrCode codifica o conteúdo em um QR Code, usando a menor versão possível para o nível de correção informado.
+// O modo (numérico, alfanumérico ou byte, em UTF-8) é escolhido de acordo com o conteúdo. Retorna um ponteiro para QrCode e um erro.
+func GerarQrCode(conteudo string, nivel NivelCorrecao) (*QrCode, error) {
+	if nivel < NivelCorrecaoL || nivel > NivelCorrecaoH {
+		return nil, fmt.Errorf("nível de correção inválido: %d", nivel)
+	}
+
+	modo := 0x4
+	switch {
+	case conteudo != "" && strings.Trim(conteudo, "0123456789") == "":
+		modo = 0x1
+	case conteudo != "" && strings.Trim(conteudo, alfanumericoQr) == "":
+		modo = 0x2
+	}
+
+	for versao := qrVersaoMinima; versao <= qrVersaoMaxima; versao++ {
+		capacidade := qrCodewordsDeDados(versao, nivel) * 8
+		e := &escritorBits{}
+		e.escrever(modo, 4)
+		if len(conteudo) >= 1<<qrBitsContador(modo, versao) {
+			continue
+		}
+		e.escrever(len(conteudo), qrBitsContador(modo, versao))
+		escreverDados(e, modo, conteudo)
+		if len(e.bits) > capacidade {
+			continue
+		}
+		return montarQrCode(e, versao, nivel), nil
+	}
+	return nil, ErrQrCodeMuitoGrande
+}
+
+// Escritor de bits, do mais significativo para o menos significativo.
+type escritorBits struct {
+	bits []bool
+}
+
+func (e *escritorBits) escrever(v, n int) {
+	for i := n - 1; i >= 0; i-- {
+		e.bits = append(e.bits, v>>i&1 == 1)
+	}
+}
+
+// Escreve o conteúdo no modo informado (numérico, alfanumérico ou byte).
+func escreverDados(e *escritorBits, modo int, conteudo string) {
+	switch modo {
+	case 0x1:
+		for i := 0; i < len(conteudo); i += 3 {
+			grupo := conteudo[i:min(i+3, len(conteudo))]
+			v := 0
+			for _, c := range grupo {
+				v = v*10 + int(c-'0')
+			}
+			e.escrever(v, [4]int{0, 4, 7, 10}[len(grupo)])
+		}
+	case 0x2:
+		for i := 0; i < len(conteudo); i += 2 {
+			if i+1 < len(conteudo) {
+				e.escrever(strings.IndexByte(alfanumericoQr, conteudo[i])*45+strings.IndexByte(alfanumericoQr, conteudo[i+1]), 11)
+				continue
+			}
+			e.escrever(strings.IndexByte(alfanumericoQr, conteudo[i]), 6)
+		}
+	default:
+		for i := 0; i < len(conteudo); i++ {
+			e.escrever(int(conteudo[i]), 8)
+		}
+	}
+}
+
+// Completa os dados com o terminador e o preenchimento até a capacidade da versão e nível, retornando os codewords de dados.
+func completarDados(e *escritorBits, versao int, nivel NivelCorrecao) []byte {
+	capacidade := qrCodewordsDeDados(versao, nivel) * 8
+	//Terminador, alinhamento em bytes e bytes de preenchimento
+	e.escrever(0, min(4, capacidade-len(e.bits)))
+	e.escrever(0, (8-len(e.bits)%8)%8)
+	for preenchimento := 0xec; len(e.bits) < capacidade; preenchimento ^= 0xec ^ 0x11 {
+		e.escrever(preenchimento, 8)
+	}
+	dados := make([]byte, capacidade/8)
+	for i, b := range e.bits {
+		if b {
+			dados[i/8] |= 1 << (7 - i%8)
+		}
+	}
+	return dados
+}
+
+// Calcula a correção de erros e desenha o QR Code com a melhor máscara.
+func montarQrCode(e *escritorBits, versao int, nivel NivelCorrecao) *QrCode {
+	codewords := intercalarBlocos(completarDados(e, versao, nivel), versao, nivel)
+
+	//Desenha os padrões funcionais e os dados, sem máscara
+	funcional := qrModulosFuncionais(versao)
+	base := desenharPadroes(versao)
+	i := 0
+	qrPercorrerDados(funcional, func(x, y int) {
+		if i < len(codewords)*8 {
+			base[y][x] = codewords[i/8]>>(7-i%8)&1 == 1
+		}
+		i++
+	})
+
+	//Escolhe a máscara com a menor penalidade
+	var melhor *QrCode
+	melhorPenalidade := 0
+	for mascara := 0; mascara < 8; mascara++ {
+		q := &QrCode{Versao: versao, Nivel: nivel, Mascara: mascara, Modulos: make([][]bool, len(base))}
+		for y := range base {
+			q.Modulos[y] = append([]bool(nil), base[y]...)
+			for x := range base[y] {
+				if !funcional[y][x] && qrMascara(mascara, x, y) {
+					q.Modulos[y][x] = !q.Modulos[y][x]
+				}
+			}
+		}
+		q.desenharFormato()
+		if p := penalidadeQr(q.Modulos); melhor == nil || p < melhorPenalidade {
+			melhor, melhorPenalidade = q, p
+		}
+	}
+	return melhor
+}
+
+// Divide os dados em blocos, calcula a correção de erros de cada bloco e intercala os codewords.
+func intercalarBlocos(dados []byte, versao int, nivel NivelCorrecao) []byte {
+	tamanhos, correcao := qrBlocos(versao, nivel)
+	gerador := reedSolomonGerador(correcao)
+	blocos := make([][]byte, len(tamanhos))
+	correcoes := make([][]byte, len(tamanhos))
+	inicio := 0
+	for b, t := range tamanhos {
+		blocos[b] = dados[inicio : inicio+t]
+		correcoes[b] = reedSolomonResto(blocos[b], gerador)
+		inicio += t
+	}
+
+	var resultado []byte
+	for pos := 0; pos < tamanhos[len(tamanhos)-1]; pos++ {
+		for b, t := range tamanhos {
+			if pos < t {
+				resultado = append(resultado, blocos[b][pos])
+			}
+		}
+	}
+	for pos := 0; pos < correcao; pos++ {
+		for b := range correcoes {
+			resultado = append(resultado, correcoes[b][pos])
+		}
+	}
+	return resultado
+}
+
+// Polinômio gerador de Reed-Solomon de grau n, com raízes α^0 até α^(n-1).
+// Os coeficientes vão do maior para o menor grau, sem o coeficiente 1 do maior grau.
+func reedSolomonGerador(n int) []byte {
+	gerador := make([]byte, n)
+	gerador[n-1] = 1
+	raiz := byte(1)
+	for i := 0; i < n; i++ {
+		for j := range gerador {
+			gerador[j] = gfMul(gerador[j], raiz)
+			if j+1 < n {
+				gerador[j] ^= gerador[j+1]
+			}
+		}
+		raiz = gfMul(raiz, 2)
+	}
+	return gerador
+}
+
+// Calcula os codewords de correção: o resto da divisão dos dados pelo polinômio gerador.
+func reedSolomonResto(dados, gerador []byte) []byte {
+	resto := make([]byte, len(gerador))
+	for _, b := range dados {
+		fator := b ^ resto[0]
+		copy(resto, resto[1:])
+		resto[len(resto)-1] = 0
+		for i, g := range gerador {
+			resto[i] ^= gfMul(g, fator)
+		}
+	}
+	return resto
+}
+
+// Desenha os padrões de localização, temporização, alinhamento, a informação de versão e o módulo escuro fixo.
+func desenharPadroes(versao int) [][]bool {
+	tamanho := qrTamanho(versao)
+	modulos := make([][]bool, tamanho)
+	for y := range modulos {
+		modulos[y] = make([]bool, tamanho)
+	}
+
+	for i := 0; i < tamanho; i++ {
+		modulos[6][i] = i%2 == 0
+		modulos[i][6] = i%2 == 0
+	}
+	for _, centro := range [][2]int{{3, 3}, {tamanho - 4, 3}, {3, tamanho - 4}} {
+		for dy := -4; dy <= 4; dy++ {
+			for dx := -4; dx <= 4; dx++ {
+				x, y := centro[0]+dx, centro[1]+dy
+				if x < 0 || y < 0 || x >= tamanho || y >= tamanho {
+					continue
+				}
+				d := max(abs(dx), abs(dy))
+				modulos[y][x] = d != 2 && d != 4
+			}
+		}
+	}
+	posicoes := qrPosicoesAlinhamento(versao)
+	ultimo := len(posicoes) - 1
+	for i, py := range posicoes {
+		for j, px := range posicoes {
+			if (i == 0 && j == 0) || (i == 0 && j == ultimo) || (i == ultimo && j == 0) {
+				continue
+			}
+			for dy := -2; dy <= 2; dy++ {
+				for dx := -2; dx <= 2; dx++ {
+					modulos[py+dy][px+dx] = max(abs(dx), abs(dy)) != 1
+				}
+			}
+		}
+	}
+	if versao >= 7 {
+		bitsVersao := qrBitsVersao(versao)
+		primeira, segunda := qrPosicoesVersao(tamanho)
+		for i := range primeira {
+			escuro := bitsVersao>>i&1 == 1
+			modulos[primeira[i][1]][primeira[i][0]] = escuro
+			modulos[segunda[i][1]][segunda[i][0]] = escuro
+		}
+	}
+	modulos[tamanho-8][8] = true
+	return modulos
+}
+
+// Desenha as duas cópias da informação de formato (nível de correção e máscara).
+func (q *QrCode) desenharFormato() {
+	bitsFormato := qrBitsFormato(q.Nivel, q.Mascara)
+	primeira, segunda := qrPosicoesFormato(len(q.Modulos))
+	for i := range primeira {
+		escuro := bitsFormato>>i&1 == 1
+		q.Modulos[primeira[i][1]][primeira[i][0]] = escuro
+		q.Modulos[segunda[i][1]][segunda[i][0]] = escuro
+	}
+}
+
+// Calcula a penalidade de uma máscara, seguindo as quatro regras da especificação.
+// Quanto menor a penalidade, mais fácil é a leitura do QR Code.
+func penalidadeQr(modulos [][]bool) int {
+	tamanho := len(modulos)
+	penalidade := 0
+	escuros := 0
+	//Padrão parecido com o de localização (1:1:3:1:1) com 4 módulos claros de um dos lados
+	padrao := []bool{true, false, true, true, true, false, true}
+	parecidoComLocalizacao := func(modulo func(i int) bool, i int) bool {
+		for k, p := range padrao {
+			if modulo(i+k) != p {
+				return false
+			}
+		}
+		antes, depois := true, true
+		for k := 1; k <= 4; k++ {
+			antes = antes && !modulo(i-k)
+			depois = depois && !modulo(i+6+k)
+		}
+		return antes || depois
+	}
+
+	for a := 0; a < tamanho; a++ {
+		linha := func(i int) bool { return i >= 0 && i < tamanho && modulos[a][i] }
+		coluna := func(i int) bool { return i >= 0 && i < tamanho && modulos[i][a] }
+		for _, modulo := range []func(int) bool{linha, coluna} {
+			sequencia := 1
+			for i := 1; i <= tamanho; i++ {
+				if i < tamanho && modulo(i) == modulo(i-1) {
+					sequencia++
+					continue
+				}
+				if sequencia >= 5 {
+					penalidade += 3 + sequencia - 5
+				}
+				sequencia = 1
+			}
+			for i := 0; i+7 <= tamanho; i++ {
+				if parecidoComLocalizacao(modulo, i) {
+					penalidade += 40
+				}
+			}
+		}
+	}
+
+	for y := 0; y < tamanho; y++ {
+		for x := 0; x < tamanho; x++ {
+			if modulos[y][x] {
+				escuros++
+			}
+			if x+1 < tamanho && y+1 < tamanho {
+				c := modulos[y][x]
+				if modulos[y][x+1] == c && modulos[y+1][x] == c && modulos[y+1][x+1] == c {
+					penalidade += 3
+				}
+			}
+		}
+	}
+
+	total := tamanho * tamanho
+	k := (abs(escuros*20-total*10)+total-1)/total - 1
+	return penalidade + k*10
+}
+
+// Imagem desenha o QR Code com as opções informadas. Se opcoes for nil, são usados os valores padrão.
+func (q *QrCode) Imagem(opcoes *OpcoesQrCode) *image.Paletted {
+	o := opcoes.comPadroes()
+	modulosComBorda := len(q.Modulos) + 2*o.Borda
+	escala := o.Tamanho / modulosComBorda
+	if escala < 1 {
+		escala = 1
+		o.Tamanho = modulosComBorda
+	}
+	//O que sobrar da divisão é distribuído na borda
+	deslocamento := (o.Tamanho - len(q.Modulos)*escala) / 2
+
+	img := image.NewPaletted(image.Rect(0, 0, o.Tamanho, o.Tamanho), color.Palette{o.CorClara, o.CorEscura})
+	for y, linha := range q.Modulos {
+		for x, escuro := range linha {
+			if !escuro {
+				continue
+			}
+			for py := 0; py < escala; py++ {
+				inicio := img.PixOffset(deslocamento+x*escala, deslocamento+y*escala+py)
+				for px := 0; px < escala; px++ {
+					img.Pix[inicio+px] = 1
+				}
+			}
+		}
+	}
+	return img
+}
+
+// EscreverPNG desenha o QR Code (veja Imagem) e escreve a imagem em w no formato PNG.
+func (q *QrCode) EscreverPNG(w io.Writer, opcoes *OpcoesQrCode) error {
+	return png.Encode(w, q.Imagem(opcoes))
+}
+
+// EscreverSVG escreve o QR Code em w no formato SVG, com Tamanho como largura e altura.
+// Os módulos escuros são desenhados em um único path, então a imagem pode ser ampliada sem perda de qualidade.
+func (q *QrCode) EscreverSVG(w io.Writer, opcoes *OpcoesQrCode) error {
+	o := opcoes.comPadroes()
+	lado := len(q.Modulos) + 2*o.Borda
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`, o.Tamanho, o.Tamanho, lado, lado)
+	fmt.Fprintf(bw, `<rect width="%d" height="%d" fill="%s"/><path fill="%s" d="`, lado, lado, corHex(o.CorClara), corHex(o.CorEscura))
+	for y, linha := range q.Modulos {
+		for x := 0; x < len(linha); x++ {
+			if !linha[x] {
+				continue
+			}
+			//Junta os módulos escuros consecutivos da linha em um só retângulo
+			inicio := x
+			for x < len(linha) && linha[x] {
+				x++
+			}
+			fmt.Fprintf(bw, "M%d %dh%dv1h-%dz", inicio+o.Borda, y+o.Borda, x-inicio, x-inicio)
+		}
+	}
+	bw.WriteString(`"/></svg>`)
+	return bw.Flush()
+}
+
+// Converte uma cor para o formato hexadecimal usado no SVG (Ex: #000000).
+func corHex(c color.Color) string {
+	r, g, b, _ := c.RGBA()
+	return fmt.Sprintf("#%02x%02x%02x", r>>8, g>>8, b>>8)
+}
@@ -0,0 +1,1047 @@
+package gufu
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	_ "image/jpeg" //Registra o formato JPEG para image.Decode
+	_ "image/png"  //Registra o formato PNG para image.Decode
+	"math"
+	"math/bits"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	ErrQrCodeNaoEncontrado    = errors.New("nenhum qr code encontrado na imagem")
+	ErrQrCodeIlegivel         = errors.New("qr code encontrado, mas não foi possível decodificá-lo")
+	ErrQrCodeModoNaoSuportado = errors.New("o qr code usa um modo de codificação não suportado")
+)
+
+// ResultadoQrCodeIdUfu contém o conteúdo lido do QR Code de uma identidade digital e os dados validados. É retornado na função ValidarQrCodeIdUfu.
+type ResultadoQrCodeIdUfu struct {
+	Conteudo string        //Conteúdo do QR Code, como lido na imagem (Ex: a URL do valida-ufu)
+	Ref      *IdDigitalRef //Id extraído do conteúdo
+	IdUfu    *IdUfu        //Dados da identidade digital, obtidos com ObterIdUfu
+}
+
+// ValidarQrCodeIdUfu localiza e lê o QR Code de uma identidade digital em uma imagem (foto ou captura de tela)
+// e valida o id com ObterIdUfu. Retorna um ponteiro para ResultadoQrCodeIdUfu e um erro.
+// Se o QR Code for lido, mas a validação falhar, o resultado é retornado com Conteudo (e Ref, se o id for válido) preenchidos junto com o erro.
+func ValidarQrCodeIdUfu(img image.Image) (*ResultadoQrCodeIdUfu, error) {
+	conteudo, err := LerQrCode(img)
+	if err != nil {
+		return nil, err
+	}
+
+	resultado := &ResultadoQrCodeIdUfu{Conteudo: conteudo}
+	resultado.Ref, err = InterpretarIdDigital(conteudo)
+	if err != nil {
+		return resultado, err
+	}
+	resultado.IdUfu, err = ObterIdUfu(resultado.Ref.Id)
+	if err != nil {
+		return resultado, err
+	}
+	return resultado, nil
+}
+
+// ValidarQrCodeIdUfuDeBytes faz o mesmo que ValidarQrCodeIdUfu, a partir dos bytes de uma imagem PNG ou JPEG.
+func ValidarQrCodeIdUfuDeBytes(dados []byte) (*ResultadoQrCodeIdUfu, error) {
+	img, _, err := image.Decode(bytes.NewReader(dados))
+	if err != nil {
+		return nil, err
+	}
+	return ValidarQrCodeIdUfu(img)
+}
+
+// LerQrCode localiza e decodifica um QR Code na imagem, retornando o seu conteúdo.
+// Retorna ErrQrCodeNaoEncontrado se nenhum QR Code for localizado e ErrQrCodeIlegivel se ele for localizado, mas não puder ser lido.
+func LerQrCode(img image.Image) (string, error) {
+	lum := luminancia(img)
+	binarizacoes := []*imagemBinaria{binarizarGlobal(lum), binarizarAdaptativo(lum)}
+
+	encontrou := false
+	var ultimoErr error
+	for _, bin := range binarizacoes {
+		for _, trio := range bin.localizarPadroes() {
+			encontrou = true
+			conteudo, err := bin.decodificarTrio(trio)
+			if err == nil {
+				return conteudo, nil
+			}
+			ultimoErr = err
+		}
+	}
+	if !encontrou {
+		return "", ErrQrCodeNaoEncontrado
+	}
+	if errors.Is(ultimoErr, ErrQrCodeModoNaoSuportado) {
+		return "", ultimoErr
+	}
+	return "", ErrQrCodeIlegivel
+}
+
+// Imagem em tons de cinza, com um byte por pixel.
+type imagemLuminancia struct {
+	largura, altura int
+	pix             []uint8
+}
+
+// Converte a imagem para tons de cinza, com um caminho rápido para as imagens decodificadas de JPEG e PNG.
+func luminancia(img image.Image) *imagemLuminancia {
+	b := img.Bounds()
+	lum := &imagemLuminancia{largura: b.Dx(), altura: b.Dy(), pix: make([]uint8, b.Dx()*b.Dy())}
+	switch m := img.(type) {
+	case *image.YCbCr:
+		for y := 0; y < lum.altura; y++ {
+			copy(lum.pix[y*lum.largura:(y+1)*lum.largura], m.Y[m.YOffset(b.Min.X, b.Min.Y+y):])
+		}
+	case *image.Gray:
+		for y := 0; y < lum.altura; y++ {
+			copy(lum.pix[y*lum.largura:(y+1)*lum.largura], m.Pix[m.PixOffset(b.Min.X, b.Min.Y+y):])
+		}
+	default:
+		for y := 0; y < lum.altura; y++ {
+			for x := 0; x < lum.largura; x++ {
+				//Os valores são pré-multiplicados pelo alfa; pixels transparentes são tratados como brancos
+				r, g, bl, a := img.At(b.Min.X+x, b.Min.Y+y).RGBA()
+				cinza := (19595*r + 38470*g + 7471*bl + 1<<15) >> 16
+				lum.pix[y*lum.largura+x] = uint8((cinza + 0xffff - a) >> 8)
+			}
+		}
+	}
+	return lum
+}
+
+// Imagem binarizada: true para pixels escuros.
+type imagemBinaria struct {
+	largura, altura int
+	pix             []bool
+}
+
+func (b *imagemBinaria) escuro(x, y int) bool {
+	if x < 0 || y < 0 || x >= b.largura || y >= b.altura {
+		return false
+	}
+	return b.pix[y*b.largura+x]
+}
+
+// Binariza a imagem com um único limiar, escolhido pelo método de Otsu. Funciona bem para capturas de tela.
+func binarizarGlobal(lum *imagemLuminancia) *imagemBinaria {
+	var histograma [256]int
+	for _, p := range lum.pix {
+		histograma[p]++
+	}
+	total := len(lum.pix)
+	soma := 0.0
+	for i, n := range histograma {
+		soma += float64(i * n)
+	}
+	var (
+		somaFundo, melhorVariancia float64
+		pesoFundo, limiar          int
+	)
+	for i, n := range histograma {
+		pesoFundo += n
+		pesoFrente := total - pesoFundo
+		if pesoFundo == 0 {
+			continue
+		}
+		if pesoFrente == 0 {
+			break
+		}
+		somaFundo += float64(i * n)
+		mediaFundo := somaFundo / float64(pesoFundo)
+		mediaFrente := (soma - somaFundo) / float64(pesoFrente)
+		variancia := float64(pesoFundo) * float64(pesoFrente) * (mediaFundo - mediaFrente) * (mediaFundo - mediaFrente)
+		if variancia > melhorVariancia {
+			melhorVariancia = variancia
+			limiar = i
+		}
+	}
+
+	bin := &imagemBinaria{largura: lum.largura, altura: lum.altura, pix: make([]bool, len(lum.pix))}
+	for i, p := range lum.pix {
+		bin.pix[i] = int(p) <= limiar
+	}
+	return bin
+}
+
+// Binariza a imagem comparando cada pixel com a média da sua vizinhança. Funciona melhor com fotos com iluminação irregular.
+func binarizarAdaptativo(lum *imagemLuminancia) *imagemBinaria {
+	w, h := lum.largura, lum.altura
+	//Imagem integral, com uma linha e uma coluna extras de zeros
+	integral := make([]int, (w+1)*(h+1))
+	for y := 0; y < h; y++ {
+		somaLinha := 0
+		for x := 0; x < w; x++ {
+			somaLinha += int(lum.pix[y*w+x])
+			integral[(y+1)*(w+1)+x+1] = integral[y*(w+1)+x+1] + somaLinha
+		}
+	}
+
+	raio := max(w, h) / 16
+	if raio < 8 {
+		raio = 8
+	}
+	bin := &imagemBinaria{largura: w, altura: h, pix: make([]bool, len(lum.pix))}
+	for y := 0; y < h; y++ {
+		y0, y1 := max(y-raio, 0), min(y+raio+1, h)
+		for x := 0; x < w; x++ {
+			x0, x1 := max(x-raio, 0), min(x+raio+1, w)
+			soma := integral[y1*(w+1)+x1] - integral[y0*(w+1)+x1] - integral[y1*(w+1)+x0] + integral[y0*(w+1)+x0]
+			area := (x1 - x0) * (y1 - y0)
+			//O pixel é escuro se estiver pelo menos 10% abaixo da média da vizinhança
+			bin.pix[y*w+x] = int(lum.pix[y*w+x])*area*10 < soma*9
+		}
+	}
+	return bin
+}
+
+// Um possível padrão de localização (os três quadrados dos cantos do QR Code).
+type padraoLocalizacao struct {
+	x, y, modulo float64 //Centro e tamanho estimado de um módulo, em pixels
+	contagem     int     //Quantas vezes o padrão foi confirmado durante a busca
+}
+
+func distancia(ax, ay, bx, by float64) float64 {
+	return math.Hypot(ax-bx, ay-by)
+}
+
+// Verifica se as contagens de pixels seguem a proporção 1:1:3:1:1 de um padrão de localização.
+func proporcaoLocalizacao(contagens [5]int) bool {
+	total := 0
+	for _, c := range contagens {
+		if c == 0 {
+			return false
+		}
+		total += c
+	}
+	if total < 7 {
+		return false
+	}
+	modulo := float64(total) / 7
+	variancia := modulo / 2
+	return math.Abs(modulo-float64(contagens[0])) < variancia &&
+		math.Abs(modulo-float64(contagens[1])) < variancia &&
+		math.Abs(3*modulo-float64(contagens[2])) < 3*variancia &&
+		math.Abs(modulo-float64(contagens[3])) < variancia &&
+		math.Abs(modulo-float64(contagens[4])) < variancia
+}
+
+// Confirma um padrão de localização cruzando o centro (cx, cy) na direção (dx, dy).
+// Retorna a posição do centro ao longo dessa direção, ou NaN se o padrão não for confirmado.
+func (b *imagemBinaria) confirmarPadrao(cx, cy, dx, dy, maximo, totalOriginal int) float64 {
+	var contagens [5]int
+	//Do centro para trás: preto, branco, preto
+	i := 0
+	for b.escuro(cx-i*dx, cy-i*dy) {
+		contagens[2]++
+		i++
+	}
+	if !b.dentro(cx-i*dx, cy-i*dy) {
+		return math.NaN()
+	}
+	for b.dentro(cx-i*dx, cy-i*dy) && !b.escuro(cx-i*dx, cy-i*dy) && contagens[1] <= maximo {
+		contagens[1]++
+		i++
+	}
+	if !b.dentro(cx-i*dx, cy-i*dy) || contagens[1] > maximo {
+		return math.NaN()
+	}
+	for b.escuro(cx-i*dx, cy-i*dy) && contagens[0] <= maximo {
+		contagens[0]++
+		i++
+	}
+	if contagens[0] > maximo {
+		return math.NaN()
+	}
+
+	//Do centro para frente: preto, branco, preto
+	i = 1
+	for b.escuro(cx+i*dx, cy+i*dy) {
+		contagens[2]++
+		i++
+	}
+	if !b.dentro(cx+i*dx, cy+i*dy) {
+		return math.NaN()
+	}
+	for b.dentro(cx+i*dx, cy+i*dy) && !b.escuro(cx+i*dx, cy+i*dy) && contagens[3] < maximo {
+		contagens[3]++
+		i++
+	}
+	if !b.dentro(cx+i*dx, cy+i*dy) || contagens[3] >= maximo {
+		return math.NaN()
+	}
+	for b.escuro(cx+i*dx, cy+i*dy) && contagens[4] < maximo {
+		contagens[4]++
+		i++
+	}
+	if contagens[4] >= maximo {
+		return math.NaN()
+	}
+
+	total := contagens[0] + contagens[1] + contagens[2] + contagens[3] + contagens[4]
+	if 5*abs(total-totalOriginal) >= 2*totalOriginal || !proporcaoLocalizacao(contagens) {
+		return math.NaN()
+	}
+	//Posição do fim do último trecho, menos metade do padrão
+	fim := i
+	return float64(fim-contagens[4]-contagens[3]) - float64(contagens[2])/2
+}
+
+func (b *imagemBinaria) dentro(x, y int) bool {
+	return x >= 0 && y >= 0 && x < b.largura && y < b.altura
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// Avalia um possível centro de padrão de localização encontrado na linha y, terminando na coluna fim.
+func (b *imagemBinaria) avaliarCentro(contagens [5]int, y, fim int, padroes []*padraoLocalizacao) []*padraoLocalizacao {
+	total := contagens[0] + contagens[1] + contagens[2] + contagens[3] + contagens[4]
+	cx := float64(fim-contagens[4]-contagens[3]) - float64(contagens[2])/2
+
+	deslocY := b.confirmarPadrao(int(cx), y, 0, 1, contagens[2], total)
+	if math.IsNaN(deslocY) {
+		return padroes
+	}
+	cy := float64(y) + deslocY
+	deslocX := b.confirmarPadrao(int(cx), int(cy), 1, 0, contagens[2], total)
+	if math.IsNaN(deslocX) {
+		return padroes
+	}
+	cx = float64(int(cx)) + deslocX
+	modulo := float64(total) / 7
+
+	for _, p := range padroes {
+		if math.Abs(cy-p.y) <= modulo && math.Abs(cx-p.x) <= modulo {
+			diferenca := math.Abs(modulo - p.modulo)
+			if diferenca <= 1 || diferenca <= p.modulo {
+				n := float64(p.contagem)
+				p.x = (p.x*n + cx) / (n + 1)
+				p.y = (p.y*n + cy) / (n + 1)
+				p.modulo = (p.modulo*n + modulo) / (n + 1)
+				p.contagem++
+				return padroes
+			}
+		}
+	}
+	return append(padroes, &padraoLocalizacao{x: cx, y: cy, modulo: modulo, contagem: 1})
+}
+
+// Procura os padrões de localização na imagem e retorna os trios mais prováveis de formarem um QR Code,
+// do mais provável para o menos provável. Cada trio é ordenado como: inferior esquerdo, superior esquerdo e superior direito.
+func (b *imagemBinaria) localizarPadroes() [][3]*padraoLocalizacao {
+	var padroes []*padraoLocalizacao
+	for y := 0; y < b.altura; y++ {
+		var contagens [5]int
+		estado := 0
+		for x := 0; x < b.largura; x++ {
+			if b.pix[y*b.largura+x] {
+				if estado&1 == 1 {
+					estado++
+				}
+				contagens[estado]++
+				continue
+			}
+			if estado&1 == 1 {
+				contagens[estado]++
+				continue
+			}
+			if estado != 4 {
+				estado++
+				contagens[estado]++
+				continue
+			}
+			if proporcaoLocalizacao(contagens) {
+				padroes = b.avaliarCentro(contagens, y, x, padroes)
+				contagens = [5]int{}
+				estado = 0
+				continue
+			}
+			contagens = [5]int{contagens[2], contagens[3], contagens[4], 1, 0}
+			estado = 3
+		}
+		if estado == 4 && proporcaoLocalizacao(contagens) {
+			padroes = b.avaliarCentro(contagens, y, b.largura, padroes)
+		}
+	}
+	if len(padroes) < 3 {
+		return nil
+	}
+
+	//Mantém apenas os padrões mais confirmados, para limitar as combinações
+	sort.Slice(padroes, func(i, j int) bool { return padroes[i].contagem > padroes[j].contagem })
+	if len(padroes) > 12 {
+		padroes = padroes[:12]
+	}
+
+	type trioPontuado struct {
+		trio      [3]*padraoLocalizacao
+		pontuacao float64
+	}
+	var trios []trioPontuado
+	for i := 0; i < len(padroes); i++ {
+		for j := i + 1; j < len(padroes); j++ {
+			for k := j + 1; k < len(padroes); k++ {
+				trio, pontuacao, ok := avaliarTrio(padroes[i], padroes[j], padroes[k])
+				if ok {
+					trios = append(trios, trioPontuado{trio, pontuacao})
+				}
+			}
+		}
+	}
+	sort.Slice(trios, func(i, j int) bool { return trios[i].pontuacao < trios[j].pontuacao })
+	if len(trios) > 5 {
+		trios = trios[:5]
+	}
+	resultado := make([][3]*padraoLocalizacao, len(trios))
+	for i, t := range trios {
+		resultado[i] = t.trio
+	}
+	return resultado
+}
+
+// Verifica se três padrões podem ser os cantos de um QR Code (um triângulo retângulo isósceles com módulos de tamanho parecido).
+// Retorna os padrões ordenados e uma pontuação, menor quanto mais próximo do ideal.
+func avaliarTrio(a, b, c *padraoLocalizacao) ([3]*padraoLocalizacao, float64, bool) {
+	menor := math.Min(a.modulo, math.Min(b.modulo, c.modulo))
+	maior := math.Max(a.modulo, math.Max(b.modulo, c.modulo))
+	if maior > menor*1.5 {
+		return [3]*padraoLocalizacao{}, 0, false
+	}
+
+	ab := distancia(a.x, a.y, b.x, b.y)
+	bc := distancia(b.x, b.y, c.x, c.y)
+	ac := distancia(a.x, a.y, c.x, c.y)
+	//O padrão superior esquerdo é o oposto ao maior lado
+	var inferior, superior, direito *padraoLocalizacao
+	var lado1, lado2, hipotenusa float64
+	switch {
+	case bc >= ab && bc >= ac:
+		superior, inferior, direito, lado1, lado2, hipotenusa = a, b, c, ab, ac, bc
+	case ac >= ab && ac >= bc:
+		superior, inferior, direito, lado1, lado2, hipotenusa = b, a, c, ab, bc, ac
+	default:
+		superior, inferior, direito, lado1, lado2, hipotenusa = c, a, b, ac, bc, ab
+	}
+	moduloMedio := (a.modulo + b.modulo + c.modulo) / 3
+	//Um QR Code tem pelo menos 21 módulos, então os centros estão a pelo menos 14 módulos de distância
+	if math.Min(lado1, lado2) < 12*moduloMedio {
+		return [3]*padraoLocalizacao{}, 0, false
+	}
+	pontuacao := math.Abs(lado1-lado2)/math.Max(lado1, lado2) + math.Abs(hipotenusa-math.Hypot(lado1, lado2))/hipotenusa
+	if pontuacao > 0.5 {
+		return [3]*padraoLocalizacao{}, 0, false
+	}
+	pontuacao += (maior - menor) / maior
+
+	//Garante a orientação: olhando do canto superior esquerdo, o direito está no sentido horário do inferior
+	produto := (direito.x-superior.x)*(inferior.y-superior.y) - (direito.y-superior.y)*(inferior.x-superior.x)
+	if produto < 0 {
+		inferior, direito = direito, inferior
+	}
+	return [3]*padraoLocalizacao{inferior, superior, direito}, pontuacao, true
+}
+
+// Transformação de perspectiva das coordenadas do QR Code (em módulos) para as coordenadas da imagem (em pixels).
+type transformacao [8]float64
+
+func (t transformacao) aplicar(u, v float64) (float64, float64) {
+	d := t[6]*u + t[7]*v + 1
+	return (t[0]*u + t[1]*v + t[2]) / d, (t[3]*u + t[4]*v + t[5]) / d
+}
+
+// Calcula a transformação que leva os quatro pontos de origem aos quatro pontos de destino, resolvendo o sistema linear por eliminação de Gauss.
+func calcularTransformacao(origem, destino [4][2]float64) (transformacao, bool) {
+	var m [8][9]float64
+	for i := 0; i < 4; i++ {
+		u, v := origem[i][0], origem[i][1]
+		x, y := destino[i][0], destino[i][1]
+		m[2*i] = [9]float64{u, v, 1, 0, 0, 0, -u * x, -v * x, x}
+		m[2*i+1] = [9]float64{0, 0, 0, u, v, 1, -u * y, -v * y, y}
+	}
+	for col := 0; col < 8; col++ {
+		pivo := col
+		for l := col + 1; l < 8; l++ {
+			if math.Abs(m[l][col]) > math.Abs(m[pivo][col]) {
+				pivo = l
+			}
+		}
+		if math.Abs(m[pivo][col]) < 1e-9 {
+			return transformacao{}, false
+		}
+		m[col], m[pivo] = m[pivo], m[col]
+		for l := 0; l < 8; l++ {
+			if l == col {
+				continue
+			}
+			fator := m[l][col] / m[col][col]
+			for c := col; c < 9; c++ {
+				m[l][c] -= fator * m[col][c]
+			}
+		}
+	}
+	var t transformacao
+	for i := range t {
+		t[i] = m[i][8] / m[i][i]
+	}
+	return t, true
+}
+
+// Procura um padrão de alinhamento (um quadrado escuro de um módulo cercado por um anel claro) perto de (ex, ey).
+func (b *imagemBinaria) localizarAlinhamento(ex, ey, modulo float64) (float64, float64, bool) {
+	for _, alcance := range []float64{4, 8, 16} {
+		raio := int(alcance * modulo)
+		x0, x1 := max(int(ex)-raio, 0), min(int(ex)+raio, b.largura-1)
+		y0, y1 := max(int(ey)-raio, 0), min(int(ey)+raio, b.altura-1)
+		melhor, mx, my := math.Inf(1), 0.0, 0.0
+		for y := y0; y <= y1; y++ {
+			for x := x0; x <= x1; x++ {
+				if !b.escuro(x, y) || b.escuro(x-1, y) {
+					continue
+				}
+				//Início de um trecho escuro: mede o trecho e o anel claro em volta
+				fim := x
+				for fim <= x1 && b.escuro(fim, y) {
+					fim++
+				}
+				cx := float64(x+fim-1) / 2
+				if !b.confereAlinhamento(cx, float64(y), modulo) {
+					continue
+				}
+				cy, ok := b.centroVerticalAlinhamento(int(cx), y, modulo)
+				if !ok || !b.confereAlinhamento(cx, cy, modulo) {
+					continue
+				}
+				if d := distancia(cx, cy, ex, ey); d < melhor {
+					melhor, mx, my = d, cx, cy
+				}
+			}
+		}
+		if !math.IsInf(melhor, 1) {
+			return mx, my, true
+		}
+	}
+	return 0, 0, false
+}
+
+// Verifica se (cx, cy) está sobre um módulo escuro cercado por módulos claros e então por módulos escuros, nas quatro direções.
+func (b *imagemBinaria) confereAlinhamento(cx, cy, modulo float64) bool {
+	for _, d := range [][2]float64{{1, 0}, {-1, 0}, {0, 1}, {0, -1}} {
+		if !b.escuro(int(cx), int(cy)) ||
+			b.escuro(int(cx+d[0]*modulo), int(cy+d[1]*modulo)) ||
+			!b.escuro(int(cx+d[0]*2*modulo), int(cy+d[1]*2*modulo)) {
+			return false
+		}
+	}
+	return true
+}
+
+// Encontra o centro vertical do trecho escuro que passa por (x, y).
+func (b *imagemBinaria) centroVerticalAlinhamento(x, y int, modulo float64) (float64, bool) {
+	topo, base := y, y
+	for b.escuro(x, topo-1) {
+		topo--
+	}
+	for b.escuro(x, base+1) {
+		base++
+	}
+	altura := float64(base - topo + 1)
+	if altura > modulo*2 || altura < modulo/2 {
+		return 0, false
+	}
+	return float64(topo+base) / 2, true
+}
+
+// Estima o tamanho de um módulo medindo os padrões de localização na direção dos outros padrões,
+// o que funciona mesmo com o QR Code rotacionado.
+func (b *imagemBinaria) tamanhoModulo(trio [3]*padraoLocalizacao) float64 {
+	inferior, superior, direito := trio[0], trio[1], trio[2]
+	soma, n := 0.0, 0
+	for _, par := range [][2]*padraoLocalizacao{{superior, direito}, {direito, superior}, {superior, inferior}, {inferior, superior}} {
+		if m := b.medirPadrao(par[0], par[1]); m > 0 {
+			soma += m
+			n++
+		}
+	}
+	if n == 0 {
+		return 0
+	}
+	return soma / float64(n)
+}
+
+// Mede a largura do padrão de localização p (7 módulos) ao longo da reta que o liga ao padrão alvo.
+// Retorna o tamanho de um módulo, ou 0 se a medição não for possível.
+func (b *imagemBinaria) medirPadrao(p, alvo *padraoLocalizacao) float64 {
+	d := distancia(p.x, p.y, alvo.x, alvo.y)
+	if d == 0 {
+		return 0
+	}
+	dx, dy := (alvo.x-p.x)/d, (alvo.y-p.y)/d
+	total := 0.0
+	for _, sentido := range []float64{1, -1} {
+		//Do centro: escuro, claro, escuro, até o claro do separador
+		estado, passo := 0, 0.5
+		t := 0.0
+		for ; estado < 3 && t < 8*p.modulo; t += passo {
+			escuro := b.escuro(int(math.Floor(p.x+sentido*t*dx)), int(math.Floor(p.y+sentido*t*dy)))
+			if escuro == (estado%2 == 1) {
+				estado++
+			}
+		}
+		if estado < 3 {
+			return 0
+		}
+		total += t
+	}
+	return total / 7
+}
+
+// Tenta decodificar o QR Code delimitado pelos três padrões de localização.
+func (b *imagemBinaria) decodificarTrio(trio [3]*padraoLocalizacao) (string, error) {
+	inferior, superior, direito := trio[0], trio[1], trio[2]
+	modulo := b.tamanhoModulo(trio)
+	if modulo <= 0 {
+		return "", ErrQrCodeIlegivel
+	}
+	//Os centros dos padrões de localização estão a 3,5 módulos das bordas
+	lado := (distancia(superior.x, superior.y, direito.x, direito.y) + distancia(superior.x, superior.y, inferior.x, inferior.y)) / 2
+	estimado := int(math.Round(lado/modulo)) + 7
+
+	//O tamanho de um QR Code é sempre 4*versão+17; testa o tamanho mais próximo e os vizinhos
+	base := estimado - (estimado-17)%4
+	if (estimado-17)%4 >= 2 {
+		base += 4
+	}
+	var ultimoErr error = ErrQrCodeIlegivel
+	for _, tamanho := range []int{base, base - 4, base + 4} {
+		versao := (tamanho - 17) / 4
+		if versao < qrVersaoMinima || versao > qrVersaoMaxima {
+			continue
+		}
+		for _, t := range b.transformacoes(trio, tamanho, modulo) {
+			conteudo, err := decodificarMatriz(b.amostrar(t, tamanho))
+			if err == nil {
+				return conteudo, nil
+			}
+			ultimoErr = err
+		}
+	}
+	return "", ultimoErr
+}
+
+// Calcula as transformações candidatas para o QR Code: primeiro usando o padrão de alinhamento (que corrige a perspectiva),
+// depois apenas com os padrões de localização.
+func (b *imagemBinaria) transformacoes(trio [3]*padraoLocalizacao, tamanho int, modulo float64) []transformacao {
+	inferior, superior, direito := trio[0], trio[1], trio[2]
+	t := float64(tamanho)
+	var resultado []transformacao
+
+	//Canto inferior direito estimado como em um paralelogramo
+	brx := direito.x + inferior.x - superior.x
+	bry := direito.y + inferior.y - superior.y
+	if tamanho > 21 {
+		//O padrão de alinhamento inferior direito fica a 6,5 módulos das bordas direita e inferior
+		fator := 1 - 3/(t-7)
+		ex := superior.x + fator*(brx-superior.x)
+		ey := superior.y + fator*(bry-superior.y)
+		if ax, ay, ok := b.localizarAlinhamento(ex, ey, modulo); ok {
+			origem := [4][2]float64{{3.5, 3.5}, {t - 3.5, 3.5}, {3.5, t - 3.5}, {t - 6.5, t - 6.5}}
+			destino := [4][2]float64{{superior.x, superior.y}, {direito.x, direito.y}, {inferior.x, inferior.y}, {ax, ay}}
+			if tr, ok := calcularTransformacao(origem, destino); ok {
+				resultado = append(resultado, tr)
+			}
+		}
+	}
+	origem := [4][2]float64{{3.5, 3.5}, {t - 3.5, 3.5}, {3.5, t - 3.5}, {t - 3.5, t - 3.5}}
+	destino := [4][2]float64{{superior.x, superior.y}, {direito.x, direito.y}, {inferior.x, inferior.y}, {brx, bry}}
+	if tr, ok := calcularTransformacao(origem, destino); ok {
+		resultado = append(resultado, tr)
+	}
+	return resultado
+}
+
+// Lê os módulos do QR Code na imagem, amostrando o centro de cada módulo.
+func (b *imagemBinaria) amostrar(t transformacao, tamanho int) [][]bool {
+	modulos := make([][]bool, tamanho)
+	for y := range modulos {
+		modulos[y] = make([]bool, tamanho)
+		for x := range modulos[y] {
+			px, py := t.aplicar(float64(x)+0.5, float64(y)+0.5)
+			modulos[y][x] = b.escuro(int(math.Floor(px)), int(math.Floor(py)))
+		}
+	}
+	return modulos
+}
+
+// Decodifica uma matriz de módulos já amostrada.
+func decodificarMatriz(modulos [][]bool) (string, error) {
+	tamanho := len(modulos)
+	versao := (tamanho - 17) / 4
+
+	nivel, mascara, ok := lerFormato(modulos)
+	if !ok {
+		return "", ErrQrCodeIlegivel
+	}
+	if versao >= 7 {
+		lida, ok := lerVersao(modulos)
+		if !ok || lida != versao {
+			return "", ErrQrCodeIlegivel
+		}
+	}
+
+	//Lê os codewords, desfazendo a máscara
+	funcional := qrModulosFuncionais(versao)
+	codewords := make([]byte, qrModulosDeDados(versao)/8)
+	i := 0
+	qrPercorrerDados(funcional, func(x, y int) {
+		if i/8 >= len(codewords) {
+			return
+		}
+		if modulos[y][x] != qrMascara(mascara, x, y) {
+			codewords[i/8] |= 1 << (7 - i%8)
+		}
+		i++
+	})
+
+	dados, err := corrigirBlocos(codewords, versao, nivel)
+	if err != nil {
+		return "", err
+	}
+	return lerSegmentos(dados, versao)
+}
+
+// Lê a informação de formato (nível de correção e máscara) de uma das duas cópias, aceitando até 3 bits errados.
+func lerFormato(modulos [][]bool) (NivelCorrecao, int, bool) {
+	primeira, segunda := qrPosicoesFormato(len(modulos))
+	for _, posicoes := range [][15][2]int{primeira, segunda} {
+		lido := 0
+		for i, p := range posicoes {
+			if modulos[p[1]][p[0]] {
+				lido |= 1 << i
+			}
+		}
+		melhor, melhorNivel, melhorMascara := 4, NivelCorrecaoL, 0
+		for nivel := NivelCorrecaoL; nivel <= NivelCorrecaoH; nivel++ {
+			for mascara := 0; mascara < 8; mascara++ {
+				if d := bits.OnesCount(uint(lido ^ qrBitsFormato(nivel, mascara))); d < melhor {
+					melhor, melhorNivel, melhorMascara = d, nivel, mascara
+				}
+			}
+		}
+		if melhor <= 3 {
+			return melhorNivel, melhorMascara, true
+		}
+	}
+	return 0, 0, false
+}
+
+// Lê a informação de versão (versão 7 em diante) de uma das duas cópias, aceitando até 3 bits errados.
+func lerVersao(modulos [][]bool) (int, bool) {
+	primeira, segunda := qrPosicoesVersao(len(modulos))
+	for _, posicoes := range [][18][2]int{primeira, segunda} {
+		lido := 0
+		for i, p := range posicoes {
+			if modulos[p[1]][p[0]] {
+				lido |= 1 << i
+			}
+		}
+		for versao := 7; versao <= qrVersaoMaxima; versao++ {
+			if bits.OnesCount(uint(lido^qrBitsVersao(versao))) <= 3 {
+				return versao, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// Separa os codewords intercalados em blocos, corrige os erros de cada bloco e junta os dados.
+func corrigirBlocos(codewords []byte, versao int, nivel NivelCorrecao) ([]byte, error) {
+	tamanhos, correcao := qrBlocos(versao, nivel)
+	blocos := make([][]byte, len(tamanhos))
+	for i, t := range tamanhos {
+		blocos[i] = make([]byte, 0, t+correcao)
+	}
+
+	//Os dados são intercalados primeiro (os blocos longos têm um codeword a mais), depois a correção
+	i := 0
+	for pos := 0; pos < tamanhos[len(tamanhos)-1]; pos++ {
+		for b, t := range tamanhos {
+			if pos < t {
+				blocos[b] = append(blocos[b], codewords[i])
+				i++
+			}
+		}
+	}
+	for pos := 0; pos < correcao; pos++ {
+		for b := range blocos {
+			blocos[b] = append(blocos[b], codewords[i])
+			i++
+		}
+	}
+
+	var dados []byte
+	for b, bloco := range blocos {
+		if !reedSolomonCorrigir(bloco, correcao) {
+			return nil, ErrQrCodeIlegivel
+		}
+		dados = append(dados, bloco[:tamanhos[b]]...)
+	}
+	return dados, nil
+}
+
+// Corrige os erros de um bloco Reed-Solomon (dados seguidos de n codewords de correção), no próprio slice.
+// Retorna false se houver mais erros do que é possível corrigir.
+func reedSolomonCorrigir(bloco []byte, n int) bool {
+	//Síndromes: o bloco avaliado nas raízes do polinômio gerador (α^0 até α^(n-1))
+	sindromes := make([]byte, n)
+	semErros := true
+	for i := range sindromes {
+		var s byte
+		for _, c := range bloco {
+			s = gfMul(s, gfPow(i)) ^ c
+		}
+		sindromes[i] = s
+		if s != 0 {
+			semErros = false
+		}
+	}
+	if semErros {
+		return true
+	}
+
+	//Berlekamp-Massey: encontra o polinômio localizador de erros (coeficientes do menor para o maior grau)
+	localizador := []byte{1}
+	anterior := []byte{1}
+	grau, passo := 0, 1
+	var discrepanciaAnterior byte = 1
+	for k := 0; k < n; k++ {
+		d := sindromes[k]
+		for i := 1; i <= grau && i < len(localizador); i++ {
+			d ^= gfMul(localizador[i], sindromes[k-i])
+		}
+		if d == 0 {
+			passo++
+			continue
+		}
+		fator := gfDiv(d, discrepanciaAnterior)
+		novo := make([]byte, max(len(localizador), len(anterior)+passo))
+		copy(novo, localizador)
+		for i, c := range anterior {
+			novo[i+passo] ^= gfMul(fator, c)
+		}
+		if 2*grau <= k {
+			anterior = localizador
+			grau = k + 1 - grau
+			discrepanciaAnterior = d
+			passo = 1
+		} else {
+			passo++
+		}
+		localizador = novo
+	}
+	if 2*grau > n {
+		return false
+	}
+
+	//Busca de Chien: as raízes do localizador indicam as posições com erro
+	var posicoes []int
+	for p := 0; p < len(bloco); p++ {
+		//Avalia o localizador em α^(-p)
+		inverso := gfPow(255 - p%255)
+		var soma, x byte = 0, 1
+		for _, c := range localizador {
+			soma ^= gfMul(c, x)
+			x = gfMul(x, inverso)
+		}
+		if soma == 0 {
+			posicoes = append(posicoes, p)
+		}
+	}
+	if len(posicoes) != grau {
+		return false
+	}
+
+	//Forney: calcula o valor de cada erro a partir do polinômio avaliador Ω(x) = S(x)Λ(x) mod x^n
+	avaliador := make([]byte, n)
+	for i := 0; i < n; i++ {
+		for j := 0; j <= i && j < len(localizador); j++ {
+			avaliador[i] ^= gfMul(localizador[j], sindromes[i-j])
+		}
+	}
+	for _, p := range posicoes {
+		xi := gfPow(p)
+		inverso := gfPow(255 - p%255)
+		var numerador, denominador, x byte = 0, 0, 1
+		for _, c := range avaliador {
+			numerador ^= gfMul(c, x)
+			x = gfMul(x, inverso)
+		}
+		//Derivada formal do localizador: apenas os termos de grau ímpar
+		x = 1
+		for i := 1; i < len(localizador); i += 2 {
+			denominador ^= gfMul(localizador[i], x)
+			x = gfMul(x, gfMul(inverso, inverso))
+		}
+		if denominador == 0 {
+			return false
+		}
+		bloco[len(bloco)-1-p] ^= gfMul(xi, gfDiv(numerador, denominador))
+	}
+	return true
+}
+
+// Leitor de bits, do mais significativo para o menos significativo.
+type leitorBits struct {
+	dados []byte
+	pos   int
+}
+
+func (l *leitorBits) restantes() int {
+	return len(l.dados)*8 - l.pos
+}
+
+func (l *leitorBits) ler(n int) int {
+	v := 0
+	for i := 0; i < n; i++ {
+		v = v<<1 | int(l.dados[l.pos/8]>>(7-l.pos%8)&1)
+		l.pos++
+	}
+	return v
+}
+
+const alfanumericoQr = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ $%*+-./:"
+
+// Quantidade de bits do contador de caracteres, por modo (numérico, alfanumérico, byte e kanji) e faixa de versões.
+func qrBitsContador(modo, versao int) int {
+	faixa := 0
+	if versao >= 27 {
+		faixa = 2
+	} else if versao >= 10 {
+		faixa = 1
+	}
+	switch modo {
+	case 0x1:
+		return [3]int{10, 12, 14}[faixa]
+	case 0x2:
+		return [3]int{9, 11, 13}[faixa]
+	case 0x4:
+		return [3]int{8, 16, 16}[faixa]
+	case 0x8:
+		return [3]int{8, 10, 12}[faixa]
+	}
+	return 0
+}
+
+// Lê os segmentos de dados (numérico, alfanumérico e byte) até o terminador.
+func lerSegmentos(dados []byte, versao int) (string, error) {
+	l := &leitorBits{dados: dados}
+	var saida []byte
+	for l.restantes() >= 4 {
+		modo := l.ler(4)
+		switch modo {
+		case 0x0: //Terminador
+			return textoQr(saida), nil
+		case 0x7: //ECI: apenas o designador é lido, o conteúdo é tratado como UTF-8 ou ISO-8859-1
+			if l.restantes() < 8 {
+				return "", ErrQrCodeIlegivel
+			}
+			primeiro := l.ler(8)
+			switch {
+			case primeiro&0x80 == 0:
+			case primeiro&0xc0 == 0x80 && l.restantes() >= 8:
+				l.ler(8)
+			case primeiro&0xe0 == 0xc0 && l.restantes() >= 16:
+				l.ler(16)
+			default:
+				return "", ErrQrCodeIlegivel
+			}
+			continue
+		case 0x3: //Structured append: ignora o cabeçalho
+			if l.restantes() < 16 {
+				return "", ErrQrCodeIlegivel
+			}
+			l.ler(16)
+			continue
+		case 0x5, 0x9: //FNC1
+			if modo == 0x9 {
+				l.ler(8)
+			}
+			continue
+		case 0x1, 0x2, 0x4:
+		default:
+			return "", ErrQrCodeModoNaoSuportado
+		}
+
+		bitsContador := qrBitsContador(modo, versao)
+		if l.restantes() < bitsContador {
+			return "", ErrQrCodeIlegivel
+		}
+		quantidade := l.ler(bitsContador)
+		switch modo {
+		case 0x1:
+			for quantidade > 0 {
+				digitos, nbits := min(quantidade, 3), [4]int{0, 4, 7, 10}[min(quantidade, 3)]
+				if l.restantes() < nbits {
+					return "", ErrQrCodeIlegivel
+				}
+				v := l.ler(nbits)
+				texto := []byte{byte('0' + v/100%10), byte('0' + v/10%10), byte('0' + v%10)}
+				saida = append(saida, texto[3-digitos:]...)
+				quantidade -= digitos
+			}
+		case 0x2:
+			for quantidade > 0 {
+				if quantidade >= 2 {
+					if l.restantes() < 11 {
+						return "", ErrQrCodeIlegivel
+					}
+					v := l.ler(11)
+					if v/45 >= len(alfanumericoQr) {
+						return "", ErrQrCodeIlegivel
+					}
+					saida = append(saida, alfanumericoQr[v/45], alfanumericoQr[v%45])
+					quantidade -= 2
+					continue
+				}
+				if l.restantes() < 6 {
+					return "", ErrQrCodeIlegivel
+				}
+				v := l.ler(6)
+				if v >= len(alfanumericoQr) {
+					return "", ErrQrCodeIlegivel
+				}
+				saida = append(saida, alfanumericoQr[v])
+				quantidade--
+			}
+		case 0x4:
+			if l.restantes() < quantidade*8 {
+				return "", ErrQrCodeIlegivel
+			}
+			for ; quantidade > 0; quantidade-- {
+				saida = append(saida, byte(l.ler(8)))
+			}
+		}
+	}
+	return textoQr(saida), nil
+}
+
+// Converte os bytes lidos em texto: UTF-8 quando válido, senão ISO-8859-1 (o padrão do modo byte).
+func textoQr(dados []byte) string {
+	if utf8.Valid(dados) {
+		return string(dados)
+	}
+	var sb strings.Builder
+	for _, b := range dados {
+		sb.WriteRune(rune(b))
+	}
+	return sb.String()
+}
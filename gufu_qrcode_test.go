@@ -0,0 +1,221 @@
+package gufu
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+// Codewords de "HELLO WORLD" em um QR Code 1-M: 16 de dados seguidos de 10 de correção.
+var codewordsHelloWorld = []byte{
+	32, 91, 11, 120, 209, 114, 220, 77, 67, 64, 236, 17, 236, 17, 236, 17,
+	196, 35, 39, 119, 235, 215, 231, 226, 93, 23,
+}
+
+func TestQrCodewordsDeDados(t *testing.T) {
+	casos := []struct {
+		versao   int
+		nivel    NivelCorrecao
+		esperado int
+	}{
+		{1, NivelCorrecaoL, 19},
+		{1, NivelCorrecaoH, 9},
+		{10, NivelCorrecaoM, 216},
+		{40, NivelCorrecaoL, 2956},
+		{40, NivelCorrecaoH, 1276},
+	}
+	for _, c := range casos {
+		if n := qrCodewordsDeDados(c.versao, c.nivel); n != c.esperado {
+			t.Errorf("versão %d, nível %d: esperado %d codewords, obtido %d", c.versao, c.nivel, c.esperado, n)
+		}
+	}
+}
+
+func TestReedSolomonCorrigir(t *testing.T) {
+	bloco := append([]byte(nil), codewordsHelloWorld...)
+	if !reedSolomonCorrigir(bloco, 10) {
+		t.Fatal("um bloco sem erros deveria ser aceito")
+	}
+
+	//10 codewords de correção corrigem até 5 erros
+	for _, p := range []int{0, 3, 15, 20, 25} {
+		bloco[p] ^= 0x5a
+	}
+	if !reedSolomonCorrigir(bloco, 10) {
+		t.Fatal("5 erros deveriam ser corrigidos")
+	}
+	for i := range bloco {
+		if bloco[i] != codewordsHelloWorld[i] {
+			t.Fatalf("codeword %d não foi corrigido: %d != %d", i, bloco[i], codewordsHelloWorld[i])
+		}
+	}
+
+	for p := 0; p < 6; p++ {
+		bloco[p*4] ^= 0xff
+	}
+	if reedSolomonCorrigir(bloco, 10) {
+		t.Fatal("6 erros não deveriam ser corrigidos")
+	}
+}
+
+func TestLerSegmentos(t *testing.T) {
+	texto, err := lerSegmentos(codewordsHelloWorld[:16], 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if texto != "HELLO WORLD" {
+		t.Fatalf("esperado HELLO WORLD, obtido %q", texto)
+	}
+}
+
+// QR Code 1-M (máscara 0) de "HELLO WORLD", montado módulo a módulo a partir de codewordsHelloWorld,
+// sem usar o gerador do gufu. '#' é um módulo escuro.
+var matrizHelloWorld = []string{
+	"#######...#.#.#######",
+	"#.....#.###...#.....#",
+	"#.###.#...#.#.#.###.#",
+	"#.###.#...#.#.#.###.#",
+	"#.###.#.#.###.#.###.#",
+	"#.....#..###..#.....#",
+	"#######.#.#.#.#######",
+	".....................",
+	"#.#.#.#..#..#...#..#.",
+	".####...#..#....#...#",
+	"...#######.#..#.##...",
+	"####.#.##..###.#.###.",
+	".#..####.#.#..###.#.#",
+	"........#.#...#...#.#",
+	"#######.....#..#.##..",
+	"#.....#..##...##.#...",
+	"#.###.#.##..#.#######",
+	"#.###.#...##.#.#...#.",
+	"#.###.#.####.###.#..#",
+	"#.....#....###...#.##",
+	"#######.##.#.###....#",
+}
+
+// Desenha a matriz com lado pixels por módulo e uma borda de 4 módulos, girada 90 graus se girar for true.
+func imagemDaMatriz(matriz []string, lado int, girar bool) image.Image {
+	tamanho := (len(matriz) + 8) * lado
+	img := image.NewGray(image.Rect(0, 0, tamanho, tamanho))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	for y, linha := range matriz {
+		for x, modulo := range linha {
+			if modulo != '#' {
+				continue
+			}
+			px, py := x, y
+			if girar {
+				px, py = len(matriz)-1-y, x
+			}
+			draw.Draw(img, image.Rect((px+4)*lado, (py+4)*lado, (px+5)*lado, (py+5)*lado), image.NewUniform(color.Black), image.Point{}, draw.Src)
+		}
+	}
+	return img
+}
+
+func TestLerQrCodeImagemFixa(t *testing.T) {
+	for _, girar := range []bool{false, true} {
+		texto, err := LerQrCode(imagemDaMatriz(matrizHelloWorld, 6, girar))
+		if err != nil {
+			t.Fatalf("girar=%v: %v", girar, err)
+		}
+		if texto != "HELLO WORLD" {
+			t.Fatalf("girar=%v: esperado HELLO WORLD, obtido %q", girar, texto)
+		}
+	}
+}
+
+func TestLerQrCodeSemQrCode(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 200, 200))
+	draw.Draw(img, img.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(img, image.Rect(50, 50, 150, 150), image.NewUniform(color.Black), image.Point{}, draw.Src)
+	if _, err := LerQrCode(img); !errors.Is(err, ErrQrCodeNaoEncontrado) {
+		t.Fatalf("esperado ErrQrCodeNaoEncontrado, obtido %v", err)
+	}
+}
+
+func TestGerarQrCodeCodewords(t *testing.T) {
+	q, err := GerarQrCode("HELLO WORLD", NivelCorrecaoM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q.Versao != 1 {
+		t.Fatalf("esperada a versão 1, obtida %d", q.Versao)
+	}
+	e := &escritorBits{}
+	e.escrever(0x2, 4)
+	e.escrever(11, qrBitsContador(0x2, 1))
+	escreverDados(e, 0x2, "HELLO WORLD")
+	codewords := intercalarBlocos(completarDados(e, 1, NivelCorrecaoM), 1, NivelCorrecaoM)
+	for i := range codewords {
+		if codewords[i] != codewordsHelloWorld[i] {
+			t.Fatalf("codeword %d: esperado %d, obtido %d", i, codewordsHelloWorld[i], codewords[i])
+		}
+	}
+}
+
+func TestQrCodeIdaEVolta(t *testing.T) {
+	conteudos := []string{
+		"https://www.sistemas.ufu.br/valida-ufu/#/id-digital/12352998224725",
+		"12352998224725",
+		"HELLO WORLD",
+		"Identidade digital — Glória",
+		strings.Repeat("gufu ", 120),
+	}
+	for _, conteudo := range conteudos {
+		for nivel := NivelCorrecaoL; nivel <= NivelCorrecaoH; nivel++ {
+			q, err := GerarQrCode(conteudo, nivel)
+			if err != nil {
+				t.Fatal(err)
+			}
+			lido, err := LerQrCode(q.Imagem(&OpcoesQrCode{Tamanho: 600}))
+			if err != nil {
+				t.Fatalf("versão %d, nível %d: %v", q.Versao, nivel, err)
+			}
+			if lido != conteudo {
+				t.Fatalf("versão %d, nível %d: esperado %q, obtido %q", q.Versao, nivel, conteudo, lido)
+			}
+		}
+	}
+}
+
+func TestQrCodeIdentidadeDigital(t *testing.T) {
+	id := IdentidadeDigital{CodigoBarra: "https://www.sistemas.ufu.br/valida-ufu/#/id-digital/12352998224725"}
+	q, err := id.QrCode(NivelCorrecaoQ)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var svg bytes.Buffer
+	if err := q.EscreverSVG(&svg, &OpcoesQrCode{Tamanho: 300}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(svg.String(), "<svg") || !strings.Contains(svg.String(), `width="300"`) {
+		t.Fatalf("svg inesperado: %.100s", svg.String())
+	}
+
+	var buf bytes.Buffer
+	if err := q.EscreverPNG(&buf, &OpcoesQrCode{Tamanho: 300, Borda: -1}); err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img.Bounds().Dx() != 300 {
+		t.Fatalf("largura esperada 300, obtida %d", img.Bounds().Dx())
+	}
+
+	if _, err := (&IdUfu{}).QrCode(NivelCorrecaoM); !errors.Is(err, ErrCodigoBarraVazio) {
+		t.Fatalf("esperado ErrCodigoBarraVazio, obtido %v", err)
+	}
+	if _, err := GerarQrCode(strings.Repeat("x", 3000), NivelCorrecaoH); !errors.Is(err, ErrQrCodeMuitoGrande) {
+		t.Fatalf("esperado ErrQrCodeMuitoGrande, obtido %v", err)
+	}
+}
@@ -0,0 +1,151 @@
+package gufu
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var (
+	TimeoutVerificacao = 10 * time.Second //Tempo máximo de cada verificação feita por Verificar, se o contexto não tiver um prazo menor.
+	DuracaoCacheSaude  = 30 * time.Second //Tempo em que HandlerSaude responde com o último relatório, sem verificar os serviços de novo.
+)
+
+// SaudeServico é o resultado da verificação de um dos sistemas da UFU.
+type SaudeServico struct {
+	Servico        Servico       `json:"servico"`
+	Url            string        `json:"url"`                      //URL verificada
+	Disponivel     bool          `json:"disponivel"`               //Se o servidor respondeu com um status menor que 500
+	Status         int           `json:"status,omitempty"`         //Status HTTP da resposta. Zero se não houve resposta
+	Latencia       time.Duration `json:"-"`                        //Tempo até receber os cabeçalhos da resposta
+	LatenciaMs     int64         `json:"latenciaMs"`               //Latencia em milissegundos, para o JSON
+	EnvelopeValido *bool         `json:"envelopeValido,omitempty"` //Apenas para o aplicativo: se a resposta ainda é descriptografada com as chaves conhecidas
+	Erro           string        `json:"erro,omitempty"`           //Motivo da falha, se houver
+	Disjuntor      string        `json:"disjuntor"`                //Estado do disjuntor do serviço (veja EstadoDoDisjuntor)
+}
+
+// Ok informa se o serviço está disponível e, quando verificado, se o envelope de criptografia ainda funciona.
+func (s SaudeServico) Ok() bool {
+	return s.Disponivel && (s.EnvelopeValido == nil || *s.EnvelopeValido)
+}
+
+// RelatorioSaude é o resultado de Verificar.
+type RelatorioSaude struct {
+	Saudavel     bool           `json:"saudavel"` //Se todos os serviços estão Ok
+	VerificadoEm time.Time      `json:"verificadoEm"`
+	Servicos     []SaudeServico `json:"servicos"` //Na ordem: SSO, aplicativo e validação
+}
+
+// Verificar verifica, ao mesmo tempo, se o SSO, a API do aplicativo e a API de validação estão respondendo.
+// As verificações são leves: uma requisição GET para cada serviço. Para o aplicativo, o cardápio de um único campus
+// (a menor resposta criptografada da API) é usado para conferir também se o envelope de criptografia ainda é
+// descriptografado com as chaves conhecidas; o conteúdo do cardápio não é lido.
+// As verificações ignoram os disjuntores, para mostrar o estado real dos serviços mesmo quando eles estão abertos.
+func Verificar(ctx context.Context) *RelatorioSaude {
+	ctx = ignorarDisjuntor(ctx)
+	verificacoes := []func(context.Context) SaudeServico{
+		func(ctx context.Context) SaudeServico { return verificarServico(ctx, ServicoSSO, ssoUrl+"/", nil) },
+		func(ctx context.Context) SaudeServico {
+			url := fmt.Sprintf("%s/api/cardapios/%d", mobileApiUrl, Campi["sm"].ID)
+			return verificarServico(ctx, ServicoMobile, url, verificarEnvelope)
+		},
+		func(ctx context.Context) SaudeServico {
+			return verificarServico(ctx, ServicoValida, validaApiUrl+"/", nil)
+		},
+	}
+
+	relatorio := &RelatorioSaude{VerificadoEm: time.Now(), Servicos: make([]SaudeServico, len(verificacoes)), Saudavel: true}
+	var wg sync.WaitGroup
+	for i, verificar := range verificacoes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			relatorio.Servicos[i] = verificar(ctx)
+		}()
+	}
+	wg.Wait()
+	for _, s := range relatorio.Servicos {
+		relatorio.Saudavel = relatorio.Saudavel && s.Ok()
+	}
+	return relatorio
+}
+
+// Faz uma requisição GET para url e preenche o resultado. Se envelope não for nil, ele é chamado com o corpo das
+// respostas com status 200 para verificar a criptografia.
+func verificarServico(ctx context.Context, servico Servico, url string, envelope func([]byte) error) SaudeServico {
+	saude := SaudeServico{Servico: servico, Url: url, Disjuntor: EstadoDoDisjuntor(servico).String()}
+	ctx, cancelar := context.WithTimeout(ctx, TimeoutVerificacao)
+	defer cancelar()
+
+	inicio := time.Now()
+	res, err := requisiçãoGenerica(ctx, servico, url, http.MethodGet, nil)
+	saude.Latencia = time.Since(inicio)
+	saude.LatenciaMs = saude.Latencia.Milliseconds()
+	if err != nil {
+		saude.Erro = err.Error()
+		return saude
+	}
+	defer res.Body.Close()
+
+	saude.Status = res.StatusCode
+	saude.Disponivel = res.StatusCode < 500
+	if !saude.Disponivel {
+		saude.Erro = fmt.Sprintf("status http: %v", res.StatusCode)
+		return saude
+	}
+	if envelope != nil && res.StatusCode == http.StatusOK {
+		corpo, err := io.ReadAll(res.Body)
+		if err == nil {
+			err = envelope(corpo)
+		}
+		valido := err == nil
+		saude.EnvelopeValido = &valido
+		if err != nil {
+			saude.Erro = err.Error()
+		}
+	}
+	return saude
+}
+
+// Confere se uma resposta do aplicativo é descriptografada para um JSON válido.
+func verificarEnvelope(corpo []byte) error {
+	texto, err := Descriptografar(string(corpo))
+	if err != nil {
+		return fmt.Errorf("falha ao descriptografar a resposta: %w", err)
+	}
+	if texto == "" || !json.Valid([]byte(texto)) {
+		return errors.New("a resposta descriptografada não é um JSON válido")
+	}
+	return nil
+}
+
+// HandlerSaude retorna um http.Handler que chama Verificar e responde com o RelatorioSaude em JSON,
+// com status 200 se todos os serviços estiverem Ok ou 503 caso contrário.
+// O relatório é reaproveitado por DuracaoCacheSaude, então verificações frequentes (Ex: de um balanceador de carga)
+// não multiplicam as requisições aos sistemas da UFU; requisições simultâneas esperam pela mesma verificação.
+func HandlerSaude() http.Handler {
+	var (
+		mu     sync.Mutex
+		ultimo *RelatorioSaude
+	)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		if ultimo == nil || time.Since(ultimo.VerificadoEm) >= DuracaoCacheSaude {
+			//Sem o cancelamento da requisição, para que um cliente que desistiu não estrague o relatório dos outros
+			ultimo = Verificar(context.WithoutCancel(r.Context()))
+		}
+		relatorio := ultimo
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-store")
+		if !relatorio.Saudavel {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(relatorio)
+	})
+}
@@ -0,0 +1,119 @@
+package gufu
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Cria uma resposta do aplicativo criptografada, no mesmo formato retornado pela API
+func respostaCriptografada(t *testing.T, texto string) string {
+	t.Helper()
+	cifrado, err := CodecPadrao.criptografarTexto(texto, "senhaDeTeste")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cifrado + CodecPadrao.MarcadorResposta + "senhaDeTeste"
+}
+
+// Aponta as URLs das APIs para servidor durante o teste, com os disjuntores fechados
+func apontarApisPara(t *testing.T, servidor *httptest.Server) {
+	t.Helper()
+	sso, mobile, valida := ssoUrl, mobileApiUrl, validaApiUrl
+	ssoUrl, mobileApiUrl, validaApiUrl = servidor.URL+"/sso", servidor.URL+"/mobile", servidor.URL+"/valida"
+	ReiniciarDisjuntores()
+	t.Cleanup(func() {
+		ssoUrl, mobileApiUrl, validaApiUrl = sso, mobile, valida
+		ReiniciarDisjuntores()
+	})
+}
+
+func TestVerificar(t *testing.T) {
+	cardapios := respostaCriptografada(t, `[{"titulo":"Cardápio"}]`)
+	var envelopeQuebrado, validaFora bool
+	var verificacoesMobile int
+	servidor := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/sso/":
+			http.Redirect(w, r, "/sso/login", http.StatusFound)
+		case "/sso/login":
+			fmt.Fprint(w, "login")
+		case "/mobile/api/cardapios/277":
+			verificacoesMobile++
+			if envelopeQuebrado {
+				fmt.Fprint(w, "resposta sem o marcador")
+				return
+			}
+			fmt.Fprint(w, cardapios)
+		case "/valida/":
+			if validaFora {
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			http.NotFound(w, r)
+		}
+	}))
+	defer servidor.Close()
+	apontarApisPara(t, servidor)
+
+	relatorio := Verificar(context.Background())
+	if !relatorio.Saudavel || len(relatorio.Servicos) != 3 {
+		t.Fatalf("todos os serviços deveriam estar ok: %+v", relatorio)
+	}
+	mobile := relatorio.Servicos[1]
+	if mobile.Servico != ServicoMobile || mobile.EnvelopeValido == nil || !*mobile.EnvelopeValido {
+		t.Fatalf("o envelope do aplicativo deveria ser verificado: %+v", mobile)
+	}
+	if relatorio.Servicos[2].Status != http.StatusNotFound || relatorio.Servicos[0].EnvelopeValido != nil {
+		t.Fatalf("resultado inesperado: %+v", relatorio.Servicos)
+	}
+
+	envelopeQuebrado, validaFora = true, true
+	resposta := httptest.NewRecorder()
+	HandlerSaude().ServeHTTP(resposta, httptest.NewRequest(http.MethodGet, "/saude", nil))
+	if resposta.Code != http.StatusServiceUnavailable {
+		t.Fatalf("esperado status 503, obtido %d", resposta.Code)
+	}
+	var r RelatorioSaude
+	if err := json.Unmarshal(resposta.Body.Bytes(), &r); err != nil {
+		t.Fatal(err)
+	}
+	if r.Saudavel || !r.Servicos[0].Ok() || r.Servicos[1].Ok() || !r.Servicos[1].Disponivel || r.Servicos[2].Disponivel {
+		t.Fatalf("relatório inesperado: %+v", r)
+	}
+
+	//Dentro de DuracaoCacheSaude, o handler reaproveita o relatório sem chamar os serviços
+	envelopeQuebrado, validaFora = false, false
+	handler, antes := HandlerSaude(), verificacoesMobile
+	for i := 0; i < 3; i++ {
+		resposta = httptest.NewRecorder()
+		handler.ServeHTTP(resposta, httptest.NewRequest(http.MethodGet, "/saude", nil))
+		if resposta.Code != http.StatusOK {
+			t.Fatalf("esperado status 200, obtido %d", resposta.Code)
+		}
+	}
+	if verificacoesMobile != antes+1 {
+		t.Fatalf("esperava 1 verificação do aplicativo, foram %d", verificacoesMobile-antes)
+	}
+	envelopeQuebrado = true
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/saude", nil))
+	if verificacoesMobile != antes+1 {
+		t.Fatal("o relatório deveria ter sido reaproveitado")
+	}
+	duracao := DuracaoCacheSaude
+	DuracaoCacheSaude = 0
+	t.Cleanup(func() { DuracaoCacheSaude = duracao })
+	resposta = httptest.NewRecorder()
+	handler.ServeHTTP(resposta, httptest.NewRequest(http.MethodGet, "/saude", nil))
+	if resposta.Code != http.StatusServiceUnavailable || verificacoesMobile != antes+2 {
+		t.Fatalf("o relatório expirado deveria ser refeito: status %d, %d verificações", resposta.Code, verificacoesMobile-antes)
+	}
+
+	servidor.Close()
+	if relatorio := Verificar(context.Background()); relatorio.Saudavel || relatorio.Servicos[0].Erro == "" {
+		t.Fatalf("serviços fora do ar deveriam ser reportados: %+v", relatorio)
+	}
+}
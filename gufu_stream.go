@@ -0,0 +1,113 @@
+package gufu
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Decoder lê uma resposta criptografada da API do aplicativo e decodifica o JSON direto para uma variável,
+// sem as conversões entre string e []byte de Descriptografar seguido de json.Unmarshal.
+// Como a senha fica no fim do envelope, a resposta inteira é lida antes de ser descriptografada, mas o texto
+// é descriptografado no mesmo buffer em que foi decodificado do base64.
+type Decoder struct {
+	codec *Codec
+	r     io.Reader
+}
+
+// NewDecoder retorna um Decoder que lê respostas de r com o CodecPadrao.
+func NewDecoder(r io.Reader) *Decoder { return CodecPadrao.NewDecoder(r) }
+
+// NewDecoder retorna um Decoder que lê respostas de r com o Codec.
+func (c *Codec) NewDecoder(r io.Reader) *Decoder { return &Decoder{codec: c, r: r} }
+
+// Decode lê toda a resposta, descriptografa e decodifica o JSON em v, como json.Unmarshal.
+// Os erros do envelope são os mesmos de Codec.Decode.
+func (d *Decoder) Decode(v any) error {
+	var corpo bytes.Buffer
+	if tamanho, ok := d.r.(interface{ Len() int }); ok {
+		//bytes.Reader, strings.Reader e bytes.Buffer informam o tamanho, evitando realocar o buffer
+		corpo.Grow(tamanho.Len() + bytes.MinRead)
+	}
+	if _, err := corpo.ReadFrom(d.r); err != nil {
+		return err
+	}
+	texto, err := d.codec.abrirEnvelopeBytes(corpo.Bytes(), d.codec.MarcadorResposta)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(texto, v)
+}
+
+// Encoder codifica uma variável em JSON e escreve o corpo de uma requisição para a API do aplicativo
+// ({"requestParams":"..."}) direto em um io.Writer, criptografando o JSON no mesmo buffer em que foi gerado.
+type Encoder struct {
+	codec *Codec
+	w     io.Writer
+}
+
+// NewEncoder retorna um Encoder que escreve requisições em w com o CodecPadrao.
+func NewEncoder(w io.Writer) *Encoder { return CodecPadrao.NewEncoder(w) }
+
+// NewEncoder retorna um Encoder que escreve requisições em w com o Codec.
+func (c *Codec) NewEncoder(w io.Writer) *Encoder { return &Encoder{codec: c, w: w} }
+
+// Encode codifica v em JSON, como json.Marshal, e escreve a requisição criptografada.
+// Para enviar um JSON já pronto, use json.RawMessage.
+func (e *Encoder) Encode(v any) error {
+	dados, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	senha, err := randomString(e.codec.Aleatorio, e.codec.TamanhoSenha)
+	if err != nil {
+		return err
+	}
+	cifrado, err := encryptAESBytes(e.codec.Salt, e.codec.IV, senha, dados, e.codec.TamanhoChave, e.codec.Iteracoes)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(e.w)
+	w.WriteString(`{"requestParams":"`)
+	b64 := base64.NewEncoder(base64.StdEncoding, w)
+	b64.Write(cifrado)
+	b64.Close()
+	w.WriteString(e.codec.MarcadorRequisicao)
+	w.WriteString(senha)
+	w.WriteString(`"}`)
+	return w.Flush()
+}
+
+// Versão de abrirEnvelope para []byte. O texto retornado usa o mesmo espaço de memória alocado para o base64.
+func (c *Codec) abrirEnvelopeBytes(envelope []byte, marcador string) ([]byte, error) {
+	if marcador == "" {
+		return nil, errors.New("o marcador do Codec está vazio")
+	}
+	inicio := bytes.LastIndex(envelope, []byte(marcador))
+	if inicio == -1 {
+		return nil, ErrEnvelopeSemMarcador
+	}
+	senha := envelope[inicio+len(marcador):]
+	if len(senha) == 0 {
+		return nil, ErrEnvelopeSemSenha
+	}
+	cifrado := envelope[:inicio]
+	buf := make([]byte, base64.StdEncoding.DecodedLen(len(cifrado)))
+	n, err := base64.StdEncoding.Decode(buf, cifrado)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCifradoInvalido, err)
+	}
+	texto, err := decryptAESBytes(c.Salt, c.IV, string(senha), buf[:n], c.TamanhoChave, c.Iteracoes)
+	if err != nil {
+		return nil, err
+	}
+	if len(texto) == 0 {
+		return nil, ErrEnvelopeVazio
+	}
+	return texto, nil
+}
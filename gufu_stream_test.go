@@ -0,0 +1,139 @@
+package gufu
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDecoderEncoder(t *testing.T) {
+	corpo := respostaCriptografada(t, `[{"titulo":"Cardápio","nid":"277"}]`)
+	var cardapios []Cardapio
+	if err := NewDecoder(strings.NewReader(corpo)).Decode(&cardapios); err != nil {
+		t.Fatal(err)
+	}
+	if len(cardapios) != 1 || cardapios[0].Nid != "277" || cardapios[0].Titulo != "Cardápio" {
+		t.Fatalf("cardápios inesperados: %+v", cardapios)
+	}
+	if err := NewDecoder(strings.NewReader("sem marcador")).Decode(&cardapios); !errors.Is(err, ErrEnvelopeSemMarcador) {
+		t.Fatalf("esperava ErrEnvelopeSemMarcador, recebeu %v", err)
+	}
+
+	var buf bytes.Buffer
+	dados := map[string]any{"login": "fulano", "idPerfil": 1}
+	if err := NewEncoder(&buf).Encode(dados); err != nil {
+		t.Fatal(err)
+	}
+	texto, err := DescriptografarRequisicao(buf.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if esperado, _ := json.Marshal(dados); texto != string(esperado) {
+		t.Fatalf("DescriptografarRequisicao = %s, esperava %s", texto, esperado)
+	}
+
+	//Com a mesma fonte de senhas, o Encoder gera a mesma saída que Codec.Encode
+	codec := *CodecPadrao
+	codec.Aleatorio = &bytesSequenciais{}
+	buf.Reset()
+	if err := codec.NewEncoder(&buf).Encode(json.RawMessage(`{"login":"fulano"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if esperado := `{"requestParams":"hYrSAXqww5W1w697HlbPA+5DHOquF9t06d3MM+MDvqg=Yckn9SAFpqM8K9B2uJYeeHZjFHhabcdefghijklmnopqrstuvwxy"}`; buf.String() != esperado {
+		t.Fatalf("Encode = %s, esperava %s", buf.String(), esperado)
+	}
+}
+
+func FuzzDecoder(f *testing.F) {
+	marcador := CodecPadrao.MarcadorResposta
+	f.Add("qb/ItbJgpIk88xrKTjGpKQ==" + marcador + "HjVSaWQaJcBxegnjiODm3vs2a")
+	f.Add("AAAAAAAAAAAAAAAAAAAAAA==" + marcador + "senha")
+	f.Add(marcador)
+	f.Fuzz(func(t *testing.T, corpo string) {
+		texto, errTexto := Descriptografar(corpo)
+		var v any
+		err := NewDecoder(strings.NewReader(corpo)).Decode(&v)
+		if errTexto != nil && err == nil {
+			t.Fatalf("Descriptografar falhou (%v) mas o Decoder não", errTexto)
+		}
+		if errTexto == nil && json.Valid([]byte(texto)) && err != nil {
+			t.Fatalf("o Decoder falhou com um JSON válido: %v", err)
+		}
+	})
+}
+
+// Resposta grande, como a lista de cardápios com uma foto em base64
+func respostaGrande(b *testing.B) string {
+	cardapios := make([]Cardapio, 300)
+	for i := range cardapios {
+		cardapios[i] = Cardapio{Titulo: "2024/12/16 - Cardápio Restaurante Universitário - Santa Mônica", PrincipalAlmoco: "Frango assado", Nid: "277"}
+	}
+	cardapios[0].Mensagem = strings.Repeat("iVBORw0KGgoAAAANSUhEUgAA", 10000)
+	dados, err := json.Marshal(cardapios)
+	if err != nil {
+		b.Fatal(err)
+	}
+	corpo, err := CriptografarResposta(string(dados))
+	if err != nil {
+		b.Fatal(err)
+	}
+	return corpo
+}
+
+func BenchmarkDescriptografarEUnmarshal(b *testing.B) {
+	corpo := []byte(respostaGrande(b))
+	b.SetBytes(int64(len(corpo)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		texto, err := Descriptografar(string(corpo))
+		if err != nil {
+			b.Fatal(err)
+		}
+		var cardapios []Cardapio
+		if err := json.Unmarshal([]byte(texto), &cardapios); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkDecoder(b *testing.B) {
+	corpo := []byte(respostaGrande(b))
+	b.SetBytes(int64(len(corpo)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for range b.N {
+		var cardapios []Cardapio
+		if err := NewDecoder(bytes.NewReader(corpo)).Decode(&cardapios); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkCriptografar(b *testing.B) {
+	dados, _ := json.Marshal(map[string]string{"foto": strings.Repeat("iVBORw0KGgoAAAANSUhEUgAA", 10000)})
+	b.SetBytes(int64(len(dados)))
+	b.ReportAllocs()
+	for range b.N {
+		corpo, err := Criptografar(string(dados))
+		if err != nil {
+			b.Fatal(err)
+		}
+		var buf bytes.Buffer
+		buf.WriteString(corpo)
+	}
+}
+
+func BenchmarkEncoder(b *testing.B) {
+	dados, _ := json.Marshal(map[string]string{"foto": strings.Repeat("iVBORw0KGgoAAAANSUhEUgAA", 10000)})
+	b.SetBytes(int64(len(dados)))
+	b.ReportAllocs()
+	for range b.N {
+		var buf bytes.Buffer
+		if err := NewEncoder(&buf).Encode(json.RawMessage(dados)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}